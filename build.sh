@@ -7,6 +7,18 @@ LINUX_AMD64=./output/${PROJECT_NAME}_linux_amd64
 LINUX_ARM64=./output/${PROJECT_NAME}_linux_arm64
 WINDOWS_AMD64=./output/${PROJECT_NAME}_windows_amd64.exe
 
+# 发布构建必须内嵌 IANA 发布的完整 RDAP 引导文件，仓库中的占位文件没有 publication
+check_rdap_bootstrap() {
+    if grep -q '"publication": ""' whois/rdap_dns.json; then
+        echo "whois/rdap_dns.json is a placeholder, run: go generate ./whois" >&2
+        exit 1
+    fi
+}
+
+if [ "$1" = "all" ] || [ "$1" = "deploy" ] || [ "$1" = "linux" ] || [ "$1" = "windows" ] || [ "$1" = "darwin" ]; then
+    check_rdap_bootstrap
+fi
+
 if [ "$1" = "all" ]; then
     echo "start to build all platforms"
     echo "build darwin amd64 to ${DARWIN_AMD64}"
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 检查点记录进度的方式
+const (
+	checkpointByDomain = "domain" // 记录每个已完成的域名，用于域名列表
+	checkpointByIndex  = "index"  // 记录连续完成的组合序号，用于模式生成的域名流
+)
+
+// checkpointIndexInterval 序号模式下连续完成序号每前进多少写入一次，域名模式下每完成多少个写入一次
+const checkpointIndexInterval = 100
+
+// checkpoint 批量查询的进度记录，中断后可以跳过已完成的部分继续查询
+// 文件按行追加，格式:
+//
+//	mode index|domain
+//	total <域名总数>
+//	done <域名>
+//	next <序号>
+type checkpoint struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+
+	mode  string
+	total int64
+	done  map[string]struct{}
+	// pendingDone 已完成但还没有写入文件的域名
+	pendingDone []string
+
+	// next 之前的序号全部完成；completed 记录 next 之后已经完成的序号
+	next      uint64
+	completed map[uint64]struct{}
+	written   uint64
+
+	// flushResults 写入进度之前调用，保存缓存的结果，保证进度不会超前于已保存的结果
+	flushResults func() error
+}
+
+// openCheckpoint 打开检查点文件，resume 为 true 时加载已有进度并继续追加，否则清空重新记录
+func openCheckpoint(path string, resume bool) (*checkpoint, error) {
+	cp := &checkpoint{
+		path:      path,
+		done:      make(map[string]struct{}),
+		completed: make(map[uint64]struct{}),
+		total:     -1,
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if err := cp.load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("读取检查点文件失败: %w", err)
+		}
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("打开检查点文件失败: %w", err)
+	}
+	cp.file = file
+	return cp, nil
+}
+
+// load 读取已有的检查点文件
+func (cp *checkpoint) load() error {
+	file, err := os.Open(cp.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		key, value, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		switch key {
+		case "":
+		case "mode":
+			cp.mode = value
+		case "total":
+			if cp.total, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return fmt.Errorf("%s:%d: 无效的总数 %q", cp.path, lineNo, value)
+			}
+		case "done":
+			cp.done[value] = struct{}{}
+		case "next":
+			next, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s:%d: 无效的序号 %q", cp.path, lineNo, value)
+			}
+			cp.next = max(cp.next, next)
+		default:
+			// 最后一行可能在崩溃时只写了一半，忽略无法识别的行
+		}
+	}
+	cp.written = cp.next
+	return scanner.Err()
+}
+
+// begin 开始一次批量查询，检查已有进度是否属于同一个任务
+// 序号模式下 [first, last) 为本次任务的序号范围，last 为 0 时不限制
+func (cp *checkpoint) begin(mode string, total int64, first, last uint64) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.mode != "" && cp.mode != mode {
+		return fmt.Errorf("检查点文件 %s 记录的是%s模式的进度，不能用于当前任务", cp.path, cp.modeName())
+	}
+	if cp.total >= 0 && total >= 0 && cp.total != total {
+		return fmt.Errorf("检查点文件 %s 记录的域名总数为 %d，当前任务为 %d，请确认使用了相同的输入", cp.path, cp.total, total)
+	}
+
+	if mode == checkpointByIndex {
+		if cp.next < first {
+			cp.next = first
+		}
+		if last > 0 && cp.next > last {
+			return fmt.Errorf("检查点文件 %s 的进度 %d 超出了当前任务的范围 %d:%d", cp.path, cp.next, first, last)
+		}
+	}
+
+	if cp.mode == "" {
+		cp.mode = mode
+		cp.total = total
+		return cp.appendLine(fmt.Sprintf("mode %s\ntotal %d", mode, total))
+	}
+	return nil
+}
+
+func (cp *checkpoint) modeName() string {
+	if cp.mode == checkpointByIndex {
+		return "模式生成"
+	}
+	return "域名列表"
+}
+
+// nextIndex 返回第一个未完成的序号
+func (cp *checkpoint) nextIndex() uint64 {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.next
+}
+
+// isDone 判断域名是否已经完成
+func (cp *checkpoint) isDone(domain string) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	_, ok := cp.done[domain]
+	return ok
+}
+
+// markDone 记录一个已完成的域名及其序号
+func (cp *checkpoint) markDone(item batchItem) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.mode == checkpointByDomain {
+		cp.done[item.domain] = struct{}{}
+		cp.pendingDone = append(cp.pendingDone, item.domain)
+		if len(cp.pendingDone) >= checkpointIndexInterval {
+			return cp.writeProgressLocked()
+		}
+		return nil
+	}
+
+	if item.index < cp.next {
+		return nil
+	}
+	cp.completed[item.index] = struct{}{}
+	for {
+		if _, ok := cp.completed[cp.next]; !ok {
+			break
+		}
+		delete(cp.completed, cp.next)
+		cp.next++
+	}
+
+	if cp.next-cp.written >= checkpointIndexInterval {
+		return cp.writeProgressLocked()
+	}
+	return nil
+}
+
+// writeProgressLocked 先保存缓存的结果，再写入最新进度
+func (cp *checkpoint) writeProgressLocked() error {
+	if cp.flushResults != nil {
+		if err := cp.flushResults(); err != nil {
+			return fmt.Errorf("保存结果失败，暂不更新进度: %w", err)
+		}
+	}
+	return cp.writeNextLocked()
+}
+
+// writeNextLocked 写入最新进度，调用方需保证对应的结果已经保存
+func (cp *checkpoint) writeNextLocked() error {
+	if len(cp.pendingDone) > 0 {
+		if err := cp.appendLine("done " + strings.Join(cp.pendingDone, "\ndone ")); err != nil {
+			return err
+		}
+		cp.pendingDone = cp.pendingDone[:0]
+	}
+	if cp.mode != checkpointByIndex || cp.next == cp.written {
+		return nil
+	}
+	if err := cp.appendLine(fmt.Sprintf("next %d", cp.next)); err != nil {
+		return err
+	}
+	cp.written = cp.next
+	return nil
+}
+
+func (cp *checkpoint) appendLine(line string) error {
+	_, err := cp.file.WriteString(line + "\n")
+	return err
+}
+
+// close 写入最新进度并关闭检查点文件，调用方需先保存所有结果
+func (cp *checkpoint) close() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.file == nil {
+		return nil
+	}
+
+	err := cp.writeNextLocked()
+	if syncErr := cp.file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := cp.file.Close(); err == nil {
+		err = closeErr
+	}
+	cp.file = nil
+	return err
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"errors"
+
+	"gois/whois"
+)
+
+// 批量查询统计中的错误类别
+const (
+	errorCategoryInvalidDomain     = "invalid_domain"
+	errorCategoryNoServer          = "no_server"
+	errorCategoryRateLimited       = "rate_limited"
+	errorCategoryTimeout           = "timeout"
+	errorCategoryConnectionRefused = "connection_refused"
+	errorCategoryDNS               = "dns"
+	errorCategoryTruncated         = "truncated"
+	errorCategoryProxy             = "proxy"
+	errorCategoryRDAP              = "rdap"
+	errorCategoryOther             = "other"
+)
+
+// errorCategory 返回查询错误在统计中的类别
+func errorCategory(err error) string {
+	var badDomain *whois.BadDomainError
+	var noWhoisServer *whois.NoWhoisServerFoundError
+	var noRDAPServer *whois.NoRDAPServerFoundError
+	var rateLimited *whois.RateLimitedError
+	var timeoutErr *whois.SocketTimeoutError
+	var refused *whois.ConnectionRefusedError
+	var dnsErr *whois.DNSError
+	var truncated *whois.TruncatedResponseError
+	var proxyErr *whois.ProxyError
+	var rdapErr *whois.RDAPError
+
+	switch {
+	case errors.As(err, &badDomain):
+		return errorCategoryInvalidDomain
+	case errors.As(err, &noWhoisServer), errors.As(err, &noRDAPServer):
+		return errorCategoryNoServer
+	case errors.As(err, &rateLimited):
+		return errorCategoryRateLimited
+	case errors.As(err, &timeoutErr):
+		return errorCategoryTimeout
+	case errors.As(err, &refused):
+		return errorCategoryConnectionRefused
+	case errors.As(err, &dnsErr):
+		return errorCategoryDNS
+	case errors.As(err, &truncated):
+		return errorCategoryTruncated
+	case errors.As(err, &proxyErr):
+		return errorCategoryProxy
+	case errors.As(err, &rdapErr):
+		if classifyError(err) == errorClassTimeout {
+			return errorCategoryTimeout
+		}
+		return errorCategoryRDAP
+	}
+	return errorCategoryOther
+}
@@ -1,12 +1,9 @@
 package cli
 
 import (
+	"context"
 	"fmt"
-	"math"
 	"os"
-	"regexp"
-	"strconv"
-	"strings"
 )
 
 // GenerateDomainsFromPattern 从模式生成域名流
@@ -16,114 +13,37 @@ import (
 // - [0-9]: 数字 0-9
 // - [abc]: 自定义字符集
 // - {n}: 重复n次
+// - {min,max}: 重复 min 到 max 次，先生成短的
+// - ?: 前一个字符或分段可有可无
+// - (a|b): 多选一，选项中可以继续使用以上语法
+// - {com,net}: 从集合中选一个，常用于后缀
+// - {@nouns}: 词表中的一个词，可以是内置词表、--wordlist 指定的名称或文件路径
+// - {@nouns:min=3,max=6,limit=100}: 按长度过滤词表，limit 只使用前若干个词
+// - \[ \] \( \) \{ \} \? \|: 转义为普通字符
 //
 // 示例:
 // - [a-z]{3}.com: 生成所有3字符小写字母域名
 // - test[0-9]{2}.net: test + 两位数字
 // - [abc]{2}.org: abc的2字符组合
+// - [a-z]{2,4}.io: 2 到 4 字符小写字母域名
+// - (shop|store|mart)[0-9]{2}.com: 三个前缀 + 两位数字
+// - brand.{com,net,org,io}: 同一名称的多个后缀
+// - get-?app.com: getapp.com 和 get-app.com
+// - {@adjectives}{@nouns:max=5}.com: 形容词 + 不超过 5 个字母的名词
+//
+// 生成的域名统一为小写并去掉只有大小写不同的重复组合，不符合 LDH 规则的组合
+// （以连字符开头或结尾、第 3、4 位为连字符、标签超过 63 个字符或总长度超过 253）会被跳过，
+// 返回的数量只包含有效组合
 func GenerateDomainsFromPattern(pattern string) (<-chan string, uint64, error) {
-	re := regexp.MustCompile(`\[([^\]]+)\](?:\{(\d+)\})?`)
-	matches := re.FindAllStringSubmatch(pattern, -1)
-
-	if len(matches) == 0 {
-		return nil, 0, fmt.Errorf("无效的模式: %s。请使用 [字符集]{重复次数} 格式，例如 [a-z]{3}.com", pattern)
-	}
-
-	matchIndices := re.FindAllStringIndex(pattern, -1)
-	var charsetGroups [][]string
-	var totalCount uint64 = 1
-	overflow := false
-
-	for i, match := range matches {
-		charsetDef := match[1]
-		repeat := 1
-		if match[2] != "" {
-			repeat, _ = strconv.Atoi(match[2])
-		}
-
-		chars, err := expandCharset(charsetDef)
-		if err != nil {
-			return nil, 0, err
-		}
-		if len(chars) == 0 {
-			return nil, 0, fmt.Errorf("字符集 [%s] 为空", charsetDef)
-		}
-
-		groupSize := uint64(len(chars))
-		for j := 0; j < repeat; j++ {
-			charsetGroups = append(charsetGroups, chars)
-			if !overflow {
-				if totalCount > math.MaxUint64/groupSize {
-					totalCount = math.MaxUint64
-					overflow = true
-				} else {
-					totalCount *= groupSize
-				}
-			}
-		}
-
-		_ = matchIndices[i]
-	}
-
-	if len(charsetGroups) == 0 {
-		return nil, 0, fmt.Errorf("无法从模式生成域名: %s", pattern)
-	}
-
-	domainChan := make(chan string, 1024)
-	go func() {
-		defer close(domainChan)
-		current := make([]string, len(charsetGroups))
-		generateCombinationsStream(pattern, matches, matchIndices, charsetGroups, 0, current, domainChan)
-	}()
-
-	return domainChan, totalCount, nil
-}
-
-// generateCombinationsStream 递归生成所有组合并写入通道
-func generateCombinationsStream(pattern string, matches [][]string, matchIndices [][]int, charsetGroups [][]string, groupIdx int, current []string, out chan<- string) {
-	if groupIdx >= len(charsetGroups) {
-		domain := buildDomain(pattern, matches, matchIndices, current)
-		out <- domain
-		return
-	}
-
-	for _, char := range charsetGroups[groupIdx] {
-		current[groupIdx] = char
-		generateCombinationsStream(pattern, matches, matchIndices, charsetGroups, groupIdx+1, current, out)
+	p, err := ParsePattern(pattern, nil)
+	if err != nil {
+		return nil, 0, err
 	}
-}
-
-// buildDomain 根据组合构建域名
-func buildDomain(pattern string, matches [][]string, matchIndices [][]int, combo []string) string {
-	domain := pattern
-
-	for i := len(matches) - 1; i >= 0; i-- {
-		match := matches[i]
-		indices := matchIndices[i]
-
-		repeat := 1
-		if match[2] != "" {
-			repeat, _ = strconv.Atoi(match[2])
-		}
-
-		segmentIdx := 0
-		for j := 0; j < i; j++ {
-			r := 1
-			if matches[j][2] != "" {
-				r, _ = strconv.Atoi(matches[j][2])
-			}
-			segmentIdx += r
-		}
-
-		var sb strings.Builder
-		for _, part := range combo[segmentIdx : segmentIdx+repeat] {
-			sb.WriteString(part)
-		}
-
-		domain = domain[:indices[0]] + sb.String() + domain[indices[1]:]
+	count, err := p.Count(context.Background(), IndexRange{Start: 0, End: p.Size()})
+	if err != nil {
+		return nil, 0, err
 	}
-
-	return domain
+	return p.Stream(context.Background(), 0, p.Size()), count, nil
 }
 
 // expandCharset 展开字符集定义
@@ -173,21 +93,21 @@ func expandCharset(charset string) ([]string, error) {
 	return chars, nil
 }
 
-// LoadDomainsFromFile 从文件加载域名列表
+// LoadDomainsFromFile 从文件加载域名列表，文件较大时使用 DomainReader 逐行读取
 func LoadDomainsFromFile(filePath string) ([]string, error) {
-	content, err := readFile(filePath)
+	file, err := OpenDomainList(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("读取文件失败: %w", err)
+		return nil, err
 	}
+	defer file.Close()
 
+	reader := NewDomainReader(file, nil)
 	var domains []string
-	lines := strings.Split(string(content), "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			domains = append(domains, line)
-		}
+	for domain := range reader.Stream(context.Background()) {
+		domains = append(domains, domain)
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
 	}
 
 	if len(domains) == 0 {
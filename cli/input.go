@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// StdinPath 表示从标准输入读取域名列表的路径
+const StdinPath = "-"
+
+// maxInputLine 域名列表中单行的最大长度
+const maxInputLine = 1 << 20
+
+// dedupeWindow 不开启 Dedupe 时记住的最近不重复域名数，与其中任意一个重复的域名都会被跳过
+const dedupeWindow = 1 << 16
+
+// InputConfig 域名列表的读取方式
+type InputConfig struct {
+	// Column 从 CSV 的第几列（从 1 开始）读取域名，0 表示整行
+	Column int
+	// JSONPath 每行是一个 JSON 对象时域名所在的字段路径，例如 host.name、records.0.domain
+	JSONPath string
+	// Dedupe 为 true 时跳过整个列表中重复的域名，需要在内存中记录所有已读取的域名；
+	// 为 false 时只跳过与最近 dedupeWindow 个不重复域名之一相同的域名，内存占用固定，
+	// 相隔更远的重复域名仍会被再次查询
+	Dedupe bool
+	// OnReject 遇到无法提取域名的行时调用
+	OnReject func(Rejection)
+}
+
+// Rejection 无法提取域名的输入行
+type Rejection struct {
+	Line   int64  // 行号，从 1 开始
+	Text   string // 行的内容
+	Reason string // 原因
+}
+
+// InputStats 读取域名列表的统计
+type InputStats struct {
+	Lines      int64 // 读取的行数，包括空行和注释
+	Accepted   int64 // 提取出的域名数（去掉重复之后）
+	Duplicates int64 // 重复的域名数
+	Rejected   int64 // 无法提取域名的行数
+}
+
+// DomainReader 逐行读取域名列表，跳过空行和 # 开头的注释行
+// 每行按配置提取主机名并归约为可注册域名，不会把整个文件读入内存，
+// 不开启 Dedupe 时只记住最近的域名用于去重，可以用固定的内存处理任意大小的文件和管道输入
+type DomainReader struct {
+	r      io.Reader
+	config *InputConfig
+	stats  InputStats
+	err    error
+}
+
+// NewDomainReader 创建从 r 读取域名列表的 DomainReader，config 为 nil 时使用默认配置
+func NewDomainReader(r io.Reader, config *InputConfig) *DomainReader {
+	if config == nil {
+		config = &InputConfig{}
+	}
+	return &DomainReader{r: r, config: config}
+}
+
+// OpenDomainList 打开域名列表文件，path 为 - 时返回标准输入
+func OpenDomainList(path string) (io.ReadCloser, error) {
+	if path == StdinPath {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	return file, nil
+}
+
+// Stream 按顺序读取域名，读完、读取失败或 ctx 取消时关闭通道
+// 通道关闭后可以通过 Err 获取读取错误，通过 Stats 获取统计
+func (d *DomainReader) Stream(ctx context.Context) <-chan string {
+	domainChan := make(chan string, 1024)
+	go func() {
+		defer close(domainChan)
+		d.err = d.each(func(domain string) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case domainChan <- domain:
+				return true
+			}
+		})
+	}()
+	return domainChan
+}
+
+// Count 读取全部内容并统计域名数
+func (d *DomainReader) Count() (int64, error) {
+	var count int64
+	d.err = d.each(func(string) bool {
+		count++
+		return true
+	})
+	return count, d.err
+}
+
+// Err 返回读取过程中遇到的错误，需要在 Stream 的通道关闭后调用
+func (d *DomainReader) Err() error {
+	return d.err
+}
+
+// Stats 返回读取统计，需要在 Stream 的通道关闭后调用
+func (d *DomainReader) Stats() InputStats {
+	return d.stats
+}
+
+// each 对每个域名调用 fn，fn 返回 false 时停止读取
+func (d *DomainReader) each(fn func(domain string) bool) error {
+	d.stats = InputStats{}
+	var seen map[string]struct{}
+	var recent *recentSet
+	if d.config.Dedupe {
+		seen = make(map[string]struct{})
+	} else {
+		recent = newRecentSet(dedupeWindow)
+	}
+
+	scanner := bufio.NewScanner(d.r)
+	scanner.Buffer(make([]byte, 64*1024), maxInputLine)
+	for scanner.Scan() {
+		d.stats.Lines++
+		line := strings.TrimSpace(scanner.Text())
+		if d.stats.Lines == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		domain, key, err := d.config.extract(line)
+		if err != nil {
+			d.stats.Rejected++
+			if d.config.OnReject != nil {
+				d.config.OnReject(Rejection{Line: d.stats.Lines, Text: line, Reason: err.Error()})
+			}
+			continue
+		}
+		// 子域名归约后相近的行经常得到同一个域名，例如 zone 文件中同一域名的多条记录
+		if seen != nil {
+			if _, ok := seen[key]; ok {
+				d.stats.Duplicates++
+				continue
+			}
+			seen[key] = struct{}{}
+		} else if !recent.add(key) {
+			d.stats.Duplicates++
+			continue
+		}
+
+		d.stats.Accepted++
+		if !fn(domain) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取域名列表失败（第 %d 行之后）: %w", d.stats.Lines, err)
+	}
+	return nil
+}
+
+// recentSet 记住最近加入的最多 size 个不重复的 key，更早的 key 按加入顺序淘汰
+type recentSet struct {
+	keys  []string // 环形缓冲区，按加入顺序保存
+	next  int      // 下一个写入位置
+	index map[string]struct{}
+}
+
+// newRecentSet 创建最多记住 size 个 key 的 recentSet
+func newRecentSet(size int) *recentSet {
+	return &recentSet{keys: make([]string, 0, size), index: make(map[string]struct{}, size)}
+}
+
+// add 加入 key，key 已经在集合中时返回 false
+func (s *recentSet) add(key string) bool {
+	if _, ok := s.index[key]; ok {
+		return false
+	}
+	if len(s.keys) < cap(s.keys) {
+		s.keys = append(s.keys, key)
+	} else {
+		delete(s.index, s.keys[s.next])
+		s.keys[s.next] = key
+		s.next = (s.next + 1) % len(s.keys)
+	}
+	s.index[key] = struct{}{}
+	return true
+}
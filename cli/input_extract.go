@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gois/whois"
+)
+
+// extract 从一行输入中提取可注册域名，返回用于查询的域名和用于去重的 A-label 形式
+// 输入是 ASCII 时返回 A-label，否则返回 Unicode 形式，与原始写法保持一致
+func (c *InputConfig) extract(line string) (string, string, error) {
+	var value string
+	var err error
+	switch {
+	case c.JSONPath != "":
+		value, err = jsonField(line, c.JSONPath)
+	case c.Column > 0:
+		value, err = csvField(line, c.Column)
+	default:
+		value = plainField(line)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	host, err := extractHost(value)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 与单个查询使用相同的校验（IDNA2008/UTS-46，只保留第 3、4 位的连字符），
+	// 不使用生成模式的 LDH 过滤，mysite--shop.com 这样的域名是可以注册的
+	ascii, unicode, err := whois.NormalizeDomain(host)
+	if err != nil {
+		var badDomain *whois.BadDomainError
+		if errors.As(err, &badDomain) && badDomain.Reason != "" {
+			return "", "", fmt.Errorf("无效的域名 %s: %s", host, badDomain.Reason)
+		}
+		return "", "", fmt.Errorf("无效的域名 %s: %w", host, err)
+	}
+
+	if isASCII(host) {
+		return ascii, ascii, nil
+	}
+	return unicode, ascii, nil
+}
+
+// plainField 普通文本行取第一个字段，去掉行内注释；
+// 这样 zone 文件、hosts 文件等以域名开头的行也可以直接使用
+func plainField(line string) string {
+	if i := strings.Index(line, " #"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, "\t#"); i >= 0 {
+		line = line[:i]
+	}
+	if fields := strings.Fields(line); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// csvField 按 CSV 解析一行并返回第 column 列（从 1 开始）
+func csvField(line string, column int) (string, error) {
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	record, err := reader.Read()
+	if err != nil {
+		return "", fmt.Errorf("CSV 解析失败: %w", err)
+	}
+	if column > len(record) {
+		return "", fmt.Errorf("只有 %d 列，没有第 %d 列", len(record), column)
+	}
+	return record[column-1], nil
+}
+
+// jsonField 按 JSON 解析一行并返回路径指向的字符串字段
+// 路径用点分隔，数组用数字下标，可以带 $. 或 . 前缀，例如 $.records.0.domain
+func jsonField(line, path string) (string, error) {
+	var value any
+	if err := json.Unmarshal([]byte(line), &value); err != nil {
+		return "", fmt.Errorf("JSON 解析失败: %w", err)
+	}
+
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			field, ok := v[key]
+			if !ok {
+				return "", fmt.Errorf("缺少字段 %s", path)
+			}
+			value = field
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("缺少字段 %s", path)
+			}
+			value = v[i]
+		default:
+			return "", fmt.Errorf("缺少字段 %s", path)
+		}
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("字段 %s 不是字符串", path)
+	}
+	return s, nil
+}
+
+// extractHost 从 URL、邮箱地址或 host:port 等形式中提取主机名，去掉端口和末尾的点
+func extractHost(value string) (string, error) {
+	value = strings.Trim(strings.TrimSpace(value), `"'<>`)
+	if value == "" {
+		return "", errors.New("没有域名")
+	}
+
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return "", fmt.Errorf("无效的 URL: %s", value)
+		}
+		if u.Port() != "" {
+			if _, err := strconv.ParseUint(u.Port(), 10, 16); err != nil {
+				return "", fmt.Errorf("无效的端口: %s", value)
+			}
+		}
+		value = u.Hostname()
+	} else {
+		value = strings.TrimPrefix(value, "mailto:")
+		// 去掉路径、查询和片段
+		if i := strings.IndexAny(value, "/?#"); i >= 0 {
+			value = value[:i]
+		}
+		// 邮箱地址或 user@host 形式
+		if i := strings.LastIndex(value, "@"); i >= 0 {
+			value = value[i+1:]
+		}
+		if strings.HasPrefix(value, "[") {
+			return "", fmt.Errorf("IP 地址不是域名: %s", value)
+		}
+		if host, port, ok := strings.Cut(value, ":"); ok && !strings.Contains(port, ":") {
+			if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+				return "", fmt.Errorf("无效的端口: %s", value)
+			}
+			value = host
+		}
+	}
+
+	host := strings.TrimRight(strings.TrimPrefix(value, "*."), ".")
+	switch {
+	case host == "":
+		return "", errors.New("没有域名")
+	case net.ParseIP(host) != nil:
+		return "", fmt.Errorf("IP 地址不是域名: %s", host)
+	}
+	return host, nil
+}
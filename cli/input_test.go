@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractHost(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "example.com", want: "example.com"},
+		{value: "example.com.", want: "example.com"},
+		{value: "*.example.com", want: "example.com"},
+		{value: "https://www.example.com:8443/path?q=1", want: "www.example.com"},
+		{value: "http://example.com:99999/", wantErr: true},
+		{value: "example.com:8080", want: "example.com"},
+		{value: "example.com:http", wantErr: true},
+		{value: "example.com/path#frag", want: "example.com"},
+		{value: "user@mail.example.com", want: "mail.example.com"},
+		{value: "mailto:user@example.org", want: "example.org"},
+		{value: "<user@example.net>", want: "example.net"},
+		{value: "192.0.2.1", wantErr: true},
+		{value: "192.0.2.1:80", wantErr: true},
+		{value: "[2001:db8::1]:443", wantErr: true},
+		{value: "http://[2001:db8::1]/", wantErr: true},
+		{value: "2001:db8::1", wantErr: true},
+		{value: ".", wantErr: true},
+		{value: `""`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := extractHost(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("extractHost(%q) = %q, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("extractHost(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestInputConfigExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  InputConfig
+		line    string
+		want    string
+		wantKey string
+		wantErr bool
+	}{
+		{name: "plain", line: "example.com", want: "example.com"},
+		{name: "subdomain reduced", line: "www.example.co.uk", want: "example.co.uk"},
+		{name: "case and trailing dot", line: "WWW.Example.COM.", want: "example.com"},
+		{name: "inline comment", line: "example.com # primary", want: "example.com"},
+		{name: "tab comment", line: "example.org\t# backup", want: "example.org"},
+		{name: "zone file line", line: "example.net. 3600 IN A 192.0.2.1", want: "example.net"},
+		{name: "url with port", line: "https://shop.example.com:8443/cart", want: "example.com"},
+		{name: "email", line: "admin@mail.example.de", want: "example.de"},
+		{name: "ip address", line: "192.0.2.1", wantErr: true},
+		{name: "public suffix only", line: "co.uk", wantErr: true},
+		{name: "idn keeps unicode", line: "www.例子.中国", want: "例子.中国", wantKey: "xn--fsqu00a.xn--fiqs8s"},
+		{name: "csv column", config: InputConfig{Column: 2}, line: `1,"https://www.example.com/",ok`, want: "example.com"},
+		{name: "csv quoted comma", config: InputConfig{Column: 3}, line: `1,"a, b",example.io`, want: "example.io"},
+		{name: "csv missing column", config: InputConfig{Column: 4}, line: "a,b,c", wantErr: true},
+		{name: "json path", config: InputConfig{JSONPath: "host.name"}, line: `{"host":{"name":"api.example.com"}}`, want: "example.com"},
+		{name: "json path array", config: InputConfig{JSONPath: "$.records.1.domain"}, line: `{"records":[{"domain":"a.com"},{"domain":"b.example.org"}]}`, want: "example.org"},
+		{name: "json missing field", config: InputConfig{JSONPath: "domain"}, line: `{"name":"example.com"}`, wantErr: true},
+		{name: "json not string", config: InputConfig{JSONPath: "domain"}, line: `{"domain":42}`, wantErr: true},
+		{name: "invalid json", config: InputConfig{JSONPath: "domain"}, line: "example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, key, err := tt.config.extract(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("extract(%q) = %q, want error", tt.line, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extract(%q): %v", tt.line, err)
+			}
+			wantKey := tt.wantKey
+			if wantKey == "" {
+				wantKey = tt.want
+			}
+			if got != tt.want || key != wantKey {
+				t.Errorf("extract(%q) = %q, %q, want %q, %q", tt.line, got, key, tt.want, wantKey)
+			}
+		})
+	}
+}
+
+func TestDomainReaderDedupe(t *testing.T) {
+	input := "example.com\nwww.example.com\nexample.org\n# comment\n\nEXAMPLE.COM.\nexample.org\nnot a domain!\n"
+	for _, dedupe := range []bool{false, true} {
+		var rejected []int64
+		reader := NewDomainReader(strings.NewReader(input), &InputConfig{
+			Dedupe:   dedupe,
+			OnReject: func(r Rejection) { rejected = append(rejected, r.Line) },
+		})
+		var got []string
+		for domain := range reader.Stream(t.Context()) {
+			got = append(got, domain)
+		}
+		if err := reader.Err(); err != nil {
+			t.Fatalf("dedupe=%v: %v", dedupe, err)
+		}
+		if want := []string{"example.com", "example.org"}; !slices.Equal(got, want) {
+			t.Errorf("dedupe=%v: got %v, want %v", dedupe, got, want)
+		}
+		stats := reader.Stats()
+		if stats.Lines != 8 || stats.Accepted != 2 || stats.Duplicates != 3 || stats.Rejected != 1 {
+			t.Errorf("dedupe=%v: stats = %+v", dedupe, stats)
+		}
+		if !slices.Equal(rejected, []int64{8}) {
+			t.Errorf("dedupe=%v: rejected lines %v, want [8]", dedupe, rejected)
+		}
+	}
+}
+
+func TestRecentSet(t *testing.T) {
+	s := newRecentSet(2)
+	steps := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", true},
+		{"a", false},
+		{"c", true}, // 淘汰 a
+		{"b", false},
+		{"a", true}, // 淘汰 b
+		{"c", false},
+		{"b", true},
+	}
+	for i, step := range steps {
+		if got := s.add(step.key); got != step.want {
+			t.Errorf("step %d: add(%q) = %v, want %v", i, step.key, got, step.want)
+		}
+	}
+}
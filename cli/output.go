@@ -0,0 +1,507 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gois/whois"
+)
+
+// 输出格式
+const (
+	FormatText   = "text"   // 完整的文本结果，normal 模式的默认格式
+	FormatCSV    = "csv"    // 每个域名一行，simple 模式的默认格式
+	FormatJSON   = "json"   // JSON 数组，每个域名一个对象
+	FormatNDJSON = "ndjson" // 每行一个 JSON 对象
+)
+
+// ResultRecord 结构化输出中的一条查询记录
+type ResultRecord struct {
+	Domain          string            `json:"domain"`
+	ASCIIDomain     string            `json:"ascii_domain,omitempty"`
+	UnicodeDomain   string            `json:"unicode_domain,omitempty"`
+	Success         bool              `json:"success"`
+	Status          string            `json:"status"`
+	Protocol        string            `json:"protocol,omitempty"`
+	Info            *whois.DomainInfo `json:"info,omitempty"`
+	WhoisServer     string            `json:"whois_server,omitempty"`
+	RegistrarServer string            `json:"registrar_server,omitempty"`
+	QueriedAt       time.Time         `json:"queried_at"`
+	DurationMS      int64             `json:"duration_ms"`
+	Attempts        int               `json:"attempts"`
+	Error           string            `json:"error,omitempty"`
+	ErrorCategory   string            `json:"error_category,omitempty"`
+	Permutation     string            `json:"permutation,omitempty"` // permute 命令生成的变体类型
+	*NameScore                        // 名称质量评分，仅在 Score 时输出
+	// 原始响应，仅在 IncludeRaw 时输出
+	RegistryResponse  string `json:"registry_response,omitempty"`
+	RegistrarResponse string `json:"registrar_response,omitempty"`
+}
+
+// DefaultCSVColumns CSV 输出的默认列
+var DefaultCSVColumns = []string{"domain", "ascii_domain", "unicode_domain", "status", "attempts"}
+
+// csvColumns CSV 输出可选的列及其取值方式
+var csvColumns = map[string]func(*ResultRecord) string{
+	"domain":            func(r *ResultRecord) string { return r.Domain },
+	"ascii_domain":      func(r *ResultRecord) string { return r.ASCIIDomain },
+	"unicode_domain":    func(r *ResultRecord) string { return r.UnicodeDomain },
+	"status":            func(r *ResultRecord) string { return r.Status },
+	"protocol":          func(r *ResultRecord) string { return r.Protocol },
+	"registrar":         func(r *ResultRecord) string { return r.info().Registrar },
+	"creation_date":     func(r *ResultRecord) string { return r.info().CreationDate },
+	"expiration_date":   func(r *ResultRecord) string { return r.info().ExpirationDate },
+	"name_servers":      func(r *ResultRecord) string { return strings.Join(r.info().NameServers, " ") },
+	"whois_server":      func(r *ResultRecord) string { return r.WhoisServer },
+	"registrar_server":  func(r *ResultRecord) string { return r.RegistrarServer },
+	"queried_at":        func(r *ResultRecord) string { return r.QueriedAt.Format(time.RFC3339) },
+	"duration_ms":       func(r *ResultRecord) string { return strconv.FormatInt(r.DurationMS, 10) },
+	"attempts":          func(r *ResultRecord) string { return strconv.Itoa(r.Attempts) },
+	"error":             func(r *ResultRecord) string { return r.Error },
+	"error_category":    func(r *ResultRecord) string { return r.ErrorCategory },
+	"permutation":       func(r *ResultRecord) string { return r.Permutation },
+	"score":             func(r *ResultRecord) string { return r.scoreField("score") },
+	"pronounceability":  func(r *ResultRecord) string { return r.scoreField("pronounceability") },
+	"keyboard_distance": func(r *ResultRecord) string { return r.scoreField("keyboard_distance") },
+}
+
+// CSVColumnNames 返回所有可选的 CSV 列名
+func CSVColumnNames() []string {
+	names := make([]string, 0, len(csvColumns))
+	for name := range csvColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scoreField 返回评分中的一项，没有评分时为空
+func (r *ResultRecord) scoreField(name string) string {
+	if r.NameScore == nil {
+		return ""
+	}
+	var value float64
+	switch name {
+	case "score":
+		value = r.Score
+	case "pronounceability":
+		value = r.Pronounceability
+	case "keyboard_distance":
+		value = r.KeyboardDistance
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// info 返回记录中的域名信息，查询失败时返回空信息
+func (r *ResultRecord) info() *whois.DomainInfo {
+	if r.Info == nil {
+		return &whois.DomainInfo{}
+	}
+	return r.Info
+}
+
+// WritesRecordsToStdout 是否将查询记录写到标准输出
+// 显式指定了结构化格式但没有输出文件时，记录写到标准输出以便直接接入管道，日志改写到标准错误
+func WritesRecordsToStdout(format, outputFile string) bool {
+	return outputFile == "" && format != "" && format != FormatText
+}
+
+// outputFormat 返回实际使用的输出格式，未指定时按查询模式选择
+func (c *CLI) outputFormat() string {
+	if c.config.Format != "" {
+		return c.config.Format
+	}
+	if c.config.Mode == "simple" {
+		return FormatCSV
+	}
+	return FormatText
+}
+
+// initOutput 打开输出目标并写入文件头
+func (c *CLI) initOutput() error {
+	switch c.outputFormat() {
+	case FormatText, FormatCSV, FormatJSON, FormatNDJSON:
+	default:
+		return fmt.Errorf("无效的输出格式: %s (可选: text, csv, json, ndjson)", c.config.Format)
+	}
+
+	c.columns = c.config.Columns
+	if len(c.columns) == 0 {
+		c.columns = DefaultCSVColumns
+	}
+	for _, column := range c.columns {
+		if _, ok := csvColumns[column]; !ok {
+			return fmt.Errorf("无效的 CSV 列: %s (可选: %s)", column, strings.Join(CSVColumnNames(), ", "))
+		}
+	}
+
+	appended := false
+	switch {
+	case c.config.OutputFile != "":
+		var err error
+		if appended, err = c.openOutputFile(); err != nil {
+			return err
+		}
+		c.out = c.outFile
+	case WritesRecordsToStdout(c.config.Format, c.config.OutputFile):
+		c.out = os.Stdout
+	default:
+		return nil
+	}
+
+	if c.outputFormat() == FormatCSV {
+		c.csvWriter = csv.NewWriter(c.out)
+	}
+	// 追加到已有文件时不重复写入文件头
+	if appended {
+		return nil
+	}
+	return c.writeHeader()
+}
+
+// openOutputFile 打开输出文件，恢复中断的任务时追加到已有内容之后，返回是否追加
+func (c *CLI) openOutputFile() (bool, error) {
+	if !c.config.Resume {
+		file, err := os.Create(c.config.OutputFile)
+		if err != nil {
+			return false, fmt.Errorf("创建输出文件失败: %w", err)
+		}
+		c.outFile = file
+		return false, nil
+	}
+
+	file, err := os.OpenFile(c.config.OutputFile, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, fmt.Errorf("打开输出文件失败: %w", err)
+	}
+	c.outFile = file
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("打开输出文件失败: %w", err)
+	}
+	size := info.Size()
+	if size == 0 {
+		return false, nil
+	}
+
+	// JSON 数组需要去掉结尾的 "]" 才能继续追加记录
+	if c.outputFormat() == FormatJSON {
+		if size, err = reopenJSONArray(file, size); err != nil {
+			return false, fmt.Errorf("无法追加到输出文件 %s: %w", c.config.OutputFile, err)
+		}
+		// 只剩下 "[" 时还没有记录
+		if size > 1 {
+			c.written = 1
+		}
+	}
+
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// reopenJSONArray 去掉 JSON 数组的结尾以便继续追加记录，返回截断后的大小
+// 数组格式为 "[" 之后每行一条记录、记录之间以 "," 分隔，最后是 "]"；
+// 上次运行中断时文件可能没有结尾的 "]"，最后一行也可能只写入了一部分，这时丢弃不完整的记录
+func reopenJSONArray(file *os.File, size int64) (int64, error) {
+	dropped := false
+	for {
+		start, line, err := readLastLine(file, size)
+		if err != nil {
+			return 0, err
+		}
+		trimmed := bytes.TrimSpace(line)
+		record := bytes.TrimSuffix(trimmed, []byte(","))
+
+		switch {
+		case start > 0 && (len(trimmed) == 0 || string(trimmed) == "]"):
+			// 结尾的空行或 "]"，连同前面的换行一起去掉
+			size = start - 1
+		case string(trimmed) == "[":
+			size = start + int64(bytes.IndexByte(line, '[')) + 1
+			return size, file.Truncate(size)
+		case len(record) > 0 && record[0] == '{' && json.Valid(record):
+			// 完整的记录，去掉后面的逗号和空白，追加时会重新写入分隔符
+			size = start + int64(bytes.Index(line, record)+len(record))
+			return size, file.Truncate(size)
+		case !dropped && start > 0 && len(trimmed) > 0 && trimmed[0] == '{':
+			// 中断时只写入了一部分的记录，丢弃它，前一行必须是完整的记录
+			dropped = true
+			size = start - 1
+		default:
+			return 0, fmt.Errorf("文件不是 JSON 数组")
+		}
+	}
+}
+
+// readLastLine 返回文件前 size 字节中最后一行的起始位置和内容，不包含行首的换行
+func readLastLine(file *os.File, size int64) (int64, []byte, error) {
+	// 从结尾向前分块查找换行
+	start := int64(0)
+	chunk := make([]byte, 4096)
+	for end := size; end > 0; {
+		n := min(end, int64(len(chunk)))
+		if _, err := file.ReadAt(chunk[:n], end-n); err != nil {
+			return 0, nil, err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			start = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+
+	line := make([]byte, size-start)
+	if _, err := file.ReadAt(line, start); err != nil {
+		return 0, nil, err
+	}
+	return start, line, nil
+}
+
+// writeHeader 写入输出头
+func (c *CLI) writeHeader() error {
+	var err error
+	switch c.outputFormat() {
+	case FormatCSV:
+		c.csvWriter.Write(c.columns)
+		c.csvWriter.Flush()
+		err = c.csvWriter.Error()
+	case FormatJSON:
+		_, err = fmt.Fprintf(c.out, "[\n")
+	case FormatText:
+		_, err = fmt.Fprintf(c.out, "# WHOIS 查询结果\n# 查询时间: %s\n# 模式: %s\n%s\n\n",
+			time.Now().Format(time.RFC3339), c.config.Mode, strings.Repeat("=", 80))
+	}
+	return err
+}
+
+// writeFooter 写入输出尾，JSON 数组需要闭合
+func (c *CLI) writeFooter() error {
+	if c.outputFormat() != FormatJSON {
+		return nil
+	}
+	if c.written > 0 {
+		if _, err := fmt.Fprintf(c.out, "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(c.out, "]\n")
+	return err
+}
+
+// newRecord 将查询结果转换为输出记录，includeRaw 为 true 时包含原始响应
+func (c *CLI) newRecord(queryResult *QueryResult, includeRaw bool) *ResultRecord {
+	record := &ResultRecord{
+		Domain:        queryResult.Domain,
+		ASCIIDomain:   queryResult.ASCIIDomain,
+		UnicodeDomain: queryResult.UnicodeDomain,
+		Success:       queryResult.Success,
+		Status:        c.resultStatus(queryResult),
+		QueriedAt:     queryResult.QueriedAt,
+		DurationMS:    queryResult.Duration.Milliseconds(),
+		Attempts:      queryResult.Attempts,
+		Permutation:   c.permutations[queryResult.Domain],
+	}
+	if c.config.Score || c.config.SortByScore {
+		score := ScoreName(queryResult.Domain)
+		record.NameScore = &score
+	}
+
+	if result := queryResult.Result; queryResult.Error == nil && result != nil {
+		record.Protocol = result.Protocol
+		record.Info = c.analyzer.GetDomainInfo(result)
+		record.WhoisServer = result.RegistryServer
+		record.RegistrarServer = result.RegistrarServer
+		if includeRaw {
+			record.RegistryResponse = result.RegistryResult
+			record.RegistrarResponse = result.RegistrarResult
+		}
+	}
+
+	if queryResult.Error != nil {
+		record.Error = queryResult.Error.Error()
+		record.ErrorCategory = errorCategory(queryResult.Error)
+	}
+
+	return record
+}
+
+// resultStatus 返回查询结果的域名状态，查询失败时为 unknown 或 rate_limited
+func (c *CLI) resultStatus(queryResult *QueryResult) string {
+	if queryResult.Error == nil && queryResult.Result != nil {
+		return c.analyzer.GetDomainStatus(queryResult.Result)
+	}
+	var rateLimited *whois.RateLimitedError
+	if errors.As(queryResult.Error, &rateLimited) {
+		return whois.StatusRateLimited
+	}
+	return whois.StatusUnknown
+}
+
+// flushResults 将结果库中缓存的结果写入数据库，并把输出文件同步到磁盘
+// 检查点写入进度之前调用，中断后恢复时不会跳过没有保存的结果
+func (c *CLI) flushResults() error {
+	if c.store != nil {
+		if err := c.store.flush(); err != nil {
+			return err
+		}
+	}
+
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+	if c.csvWriter != nil {
+		c.csvWriter.Flush()
+		if err := c.csvWriter.Error(); err != nil {
+			return err
+		}
+	}
+	if c.outFile != nil {
+		return c.outFile.Sync()
+	}
+	return nil
+}
+
+// writeResult 将结果写入输出
+func (c *CLI) writeResult(queryResult *QueryResult) {
+	if c.store != nil {
+		if err := c.store.save(c.newRecord(queryResult, true)); err != nil {
+			c.logger.Error("写入结果库失败", "domain", queryResult.Domain, "error", err)
+		}
+	}
+
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+
+	if c.out == nil {
+		return
+	}
+	if c.config.SortByScore {
+		// 渲染后暂存到临时文件，批量查询在写入后会释放 Result，内存中只保留评分
+		data, err := c.renderResult(queryResult)
+		if err == nil {
+			err = c.sorted.add(ScoreName(queryResult.Domain).Score, data)
+		}
+		if err != nil {
+			c.logger.Error("写入结果失败", "domain", queryResult.Domain, "error", err)
+		}
+		return
+	}
+	c.writeResultLocked(queryResult)
+}
+
+// writeSortedLocked 按评分从高到低写入暂存的结果，评分相同时保持查询完成的顺序
+func (c *CLI) writeSortedLocked() error {
+	err := c.sorted.each(c.writeRenderedLocked)
+	if closeErr := c.sorted.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeResultLocked 将结果按输出格式写入，调用方需持有 fileLock
+func (c *CLI) writeResultLocked(queryResult *QueryResult) {
+	data, err := c.renderResult(queryResult)
+	if err == nil {
+		err = c.writeRenderedLocked(data)
+	}
+	if err != nil {
+		c.logger.Error("写入结果失败", "domain", queryResult.Domain, "error", err)
+	}
+}
+
+// renderResult 将结果按输出格式渲染为一条记录
+func (c *CLI) renderResult(queryResult *QueryResult) ([]byte, error) {
+	var buf bytes.Buffer
+	switch c.outputFormat() {
+	case FormatCSV:
+		record := c.newRecord(queryResult, false)
+		row := make([]string, len(c.columns))
+		for i, column := range c.columns {
+			row[i] = csvColumns[column](record)
+		}
+		// 按 RFC 4180 对含逗号、引号或换行的值加引号
+		writer := csv.NewWriter(&buf)
+		writer.Write(row)
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, err
+		}
+	case FormatJSON, FormatNDJSON:
+		data, err := json.Marshal(c.newRecord(queryResult, c.config.IncludeRaw))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		if c.outputFormat() == FormatNDJSON {
+			buf.WriteByte('\n')
+		}
+	default:
+		c.writeText(&buf, queryResult)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeRenderedLocked 写入一条渲染后的记录，json 格式在记录之间添加逗号分隔
+func (c *CLI) writeRenderedLocked(data []byte) error {
+	if c.outputFormat() == FormatJSON && c.written > 0 {
+		if _, err := io.WriteString(c.out, ",\n"); err != nil {
+			return err
+		}
+	}
+	if _, err := c.out.Write(data); err != nil {
+		return err
+	}
+	c.written++
+	return nil
+}
+
+// writeText 写入完整的文本结果
+func (c *CLI) writeText(w io.Writer, queryResult *QueryResult) {
+	domain := queryResult.Domain
+	result := queryResult.Result
+	err := queryResult.Error
+
+	fmt.Fprintf(w, "\n%s\n", strings.Repeat("=", 80))
+	fmt.Fprintf(w, "域名: %s\n", domain)
+	if queryResult.ASCIIDomain != "" && queryResult.ASCIIDomain != domain {
+		fmt.Fprintf(w, "查询域名: %s (%s)\n", queryResult.ASCIIDomain, queryResult.UnicodeDomain)
+	}
+	fmt.Fprintf(w, "查询时间: %s\n", queryResult.QueriedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "查询次数: %d\n", queryResult.Attempts)
+	if kind := c.permutations[domain]; kind != "" {
+		fmt.Fprintf(w, "变体类型: %s\n", kind)
+	}
+
+	if err != nil {
+		fmt.Fprintf(w, "错误: %v\n", err)
+	} else if result != nil {
+		fmt.Fprintf(w, "\n注册商 WHOIS 服务器结果:\n")
+		fmt.Fprintf(w, "%s\n", strings.Repeat("-", 80))
+		if result.RegistrarResult != "" {
+			fmt.Fprintf(w, "%s\n", result.RegistrarResult)
+		} else {
+			fmt.Fprintf(w, "无数据\n")
+		}
+
+		fmt.Fprintf(w, "\n\n注册局 WHOIS 服务器结果:\n")
+		fmt.Fprintf(w, "%s\n", strings.Repeat("-", 80))
+		if result.RegistryResult != "" {
+			fmt.Fprintf(w, "%s\n", result.RegistryResult)
+		} else {
+			fmt.Fprintf(w, "无数据\n")
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s\n", strings.Repeat("=", 80))
+}
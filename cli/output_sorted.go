@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// sortedOutput 按评分排序输出时暂存的结果
+// 每个结果按输出格式渲染后追加到临时文件，内存中只保留评分和位置，
+// 原始 WHOIS 响应等内容不会留在内存中；结束时按评分排序后从临时文件读回写入输出
+type sortedOutput struct {
+	file    *os.File
+	size    int64
+	entries []sortedEntry
+}
+
+// sortedEntry 一个暂存结果的评分和在临时文件中的位置
+type sortedEntry struct {
+	score  float64
+	offset int64
+	length int
+}
+
+// add 暂存一个渲染后的结果，第一次调用时创建临时文件
+func (s *sortedOutput) add(score float64, data []byte) error {
+	if s.file == nil {
+		file, err := os.CreateTemp("", "gois-sorted-*")
+		if err != nil {
+			return fmt.Errorf("创建排序临时文件失败: %w", err)
+		}
+		s.file = file
+	}
+
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("写入排序临时文件失败: %w", err)
+	}
+	s.entries = append(s.entries, sortedEntry{score: score, offset: s.size, length: len(data)})
+	s.size += int64(len(data))
+	return nil
+}
+
+// each 按评分从高到低依次读回暂存的结果，评分相同时保持暂存的顺序
+func (s *sortedOutput) each(fn func(data []byte) error) error {
+	sort.SliceStable(s.entries, func(i, j int) bool {
+		return s.entries[i].score > s.entries[j].score
+	})
+
+	var buf []byte
+	for _, entry := range s.entries {
+		if cap(buf) < entry.length {
+			buf = make([]byte, entry.length)
+		}
+		data := buf[:entry.length]
+		if _, err := s.file.ReadAt(data, entry.offset); err != nil {
+			return fmt.Errorf("读取排序临时文件失败: %w", err)
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close 删除临时文件
+func (s *sortedOutput) close() error {
+	s.entries = nil
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if removeErr := os.Remove(s.file.Name()); err == nil {
+		err = removeErr
+	}
+	s.file = nil
+	return err
+}
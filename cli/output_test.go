@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReopenJSONArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"complete", "[\n{\"a\":1},\n{\"a\":2}\n]\n", "[\n{\"a\":1},\n{\"a\":2}", false},
+		{"empty array", "[\n]\n", "[", false},
+		{"header only", "[\n", "[", false},
+		{"missing bracket", "[\n{\"a\":1},\n{\"a\":2}", "[\n{\"a\":1},\n{\"a\":2}", false},
+		{"partial record", "[\n{\"a\":1},\n{\"a\":2},\n{\"a\":", "[\n{\"a\":1},\n{\"a\":2}", false},
+		{"separator without record", "[\n{\"a\":1},\n", "[\n{\"a\":1}", false},
+		{"partial first record", "[\n{\"domain\":\"exa", "[", false},
+		{"not json", "domain,status\nexample.com,ok\n", "", true},
+		{"two partial lines", "[\n{\"a\":1,\n{\"a\":", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.OpenFile(path, os.O_RDWR, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			size, err := reopenJSONArray(file, int64(len(tt.content)))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("reopenJSONArray succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reopenJSONArray: %v", err)
+			}
+			data, _ := os.ReadFile(path)
+			if string(data) != tt.want || size != int64(len(tt.want)) {
+				t.Errorf("reopenJSONArray left %q (size %d), want %q", data, size, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,633 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// maxPatternRepeat 单个分段的最大重复次数，与域名的最大长度一致
+const maxPatternRepeat = 253
+
+// patternQuantifierRegexp 匹配 {n} 和 {min,max} 的内容
+var patternQuantifierRegexp = regexp.MustCompile(`^(\d+)(?:,(\d+))?$`)
+
+// Pattern 解析后的域名生成模式
+// 所有组合按固定顺序编号：拼接的分段按混合进制排列，最后一个分段变化最快；
+// 长度范围先短后长；多选一按书写顺序。因此可以直接计算任意序号对应的域名
+// 域名不区分大小写，解析时统一转为小写并去掉重复的字符和选项；生成时跳过不符合 LDH 规则的组合，
+// 序号仍按全部组合编号，分片和检查点不受过滤影响
+type Pattern struct {
+	source string
+	root   segment
+	size   uint64
+	// allValid 所有组合都一定符合 LDH 规则
+	allValid bool
+	filter   *NameFilter
+
+	mu     sync.Mutex
+	counts map[IndexRange]uint64 // 已统计的有效组合数
+}
+
+// segment 模式中的一个分段，按序号取值
+type segment interface {
+	// size 返回分段的取值个数
+	size() uint64
+	// write 将第 index 个取值写入 b，index 必须小于 size()
+	write(b *strings.Builder, index uint64)
+}
+
+// literalSegment 固定文本
+type literalSegment string
+
+func (s literalSegment) size() uint64                           { return 1 }
+func (s literalSegment) write(b *strings.Builder, index uint64) { b.WriteString(string(s)) }
+
+// setSegment 从若干固定文本中选一个，用于字符集 [abc]、集合 {com,net} 和词表
+type setSegment []string
+
+// newSetSegment 将选项转为小写并去重，保持原有顺序
+func newSetSegment(options []string) setSegment {
+	set := make(setSegment, 0, len(options))
+	seen := make(map[string]struct{}, len(options))
+	for _, option := range options {
+		option = strings.ToLower(option)
+		if _, ok := seen[option]; !ok {
+			seen[option] = struct{}{}
+			set = append(set, option)
+		}
+	}
+	return set
+}
+
+func (s setSegment) size() uint64                           { return uint64(len(s)) }
+func (s setSegment) write(b *strings.Builder, index uint64) { b.WriteString(s[index]) }
+
+// sequenceSegment 依次拼接的多个分段，最后一个分段变化最快
+type sequenceSegment struct {
+	items   []segment
+	strides []uint64 // strides[i] 为 items[i] 之后所有分段取值个数的乘积
+	count   uint64
+}
+
+func newSequenceSegment(items []segment) (*sequenceSegment, error) {
+	strides := make([]uint64, len(items))
+	count := uint64(1)
+	for i := len(items) - 1; i >= 0; i-- {
+		strides[i] = count
+		var ok bool
+		if count, ok = mulUint64(count, items[i].size()); !ok {
+			return nil, errPatternTooLarge
+		}
+	}
+	return &sequenceSegment{items: items, strides: strides, count: count}, nil
+}
+
+func (s *sequenceSegment) size() uint64 { return s.count }
+
+func (s *sequenceSegment) write(b *strings.Builder, index uint64) {
+	for i, item := range s.items {
+		item.write(b, index/s.strides[i])
+		index %= s.strides[i]
+	}
+}
+
+// choiceSegment 多选一 (a|b|c)，按书写顺序排列，每个选项本身可以有多个取值
+type choiceSegment struct {
+	options []segment
+	offsets []uint64 // offsets[i] 为 options[i] 第一个取值的序号
+	count   uint64
+}
+
+func newChoiceSegment(options []segment) (*choiceSegment, error) {
+	offsets := make([]uint64, len(options))
+	count := uint64(0)
+	for i, option := range options {
+		offsets[i] = count
+		var ok bool
+		if count, ok = addUint64(count, option.size()); !ok {
+			return nil, errPatternTooLarge
+		}
+	}
+	return &choiceSegment{options: options, offsets: offsets, count: count}, nil
+}
+
+func (s *choiceSegment) size() uint64 { return s.count }
+
+func (s *choiceSegment) write(b *strings.Builder, index uint64) {
+	i := sort.Search(len(s.offsets), func(i int) bool { return s.offsets[i] > index }) - 1
+	s.options[i].write(b, index-s.offsets[i])
+}
+
+// repeatSegment 分段重复 min 到 max 次，先短后长，重复的各个位置中第一个变化最慢
+type repeatSegment struct {
+	elem    segment
+	min     int
+	max     int
+	powers  []uint64 // powers[k] 为重复 k 次的组合数
+	offsets []uint64 // offsets[k-min] 为重复 k 次的第一个序号
+	count   uint64
+}
+
+func newRepeatSegment(elem segment, minRepeat, maxRepeat int) (*repeatSegment, error) {
+	s := &repeatSegment{
+		elem:    elem,
+		min:     minRepeat,
+		max:     maxRepeat,
+		powers:  make([]uint64, maxRepeat+1),
+		offsets: make([]uint64, 0, maxRepeat-minRepeat+1),
+	}
+
+	s.powers[0] = 1
+	for k := 1; k <= maxRepeat; k++ {
+		var ok bool
+		if s.powers[k], ok = mulUint64(s.powers[k-1], elem.size()); !ok {
+			return nil, errPatternTooLarge
+		}
+	}
+	for k := minRepeat; k <= maxRepeat; k++ {
+		s.offsets = append(s.offsets, s.count)
+		var ok bool
+		if s.count, ok = addUint64(s.count, s.powers[k]); !ok {
+			return nil, errPatternTooLarge
+		}
+	}
+	return s, nil
+}
+
+func (s *repeatSegment) size() uint64 { return s.count }
+
+func (s *repeatSegment) write(b *strings.Builder, index uint64) {
+	k := s.min
+	for k < s.max && index >= s.offsets[k+1-s.min] {
+		k++
+	}
+	index -= s.offsets[k-s.min]
+	for j := k - 1; j >= 0; j-- {
+		s.elem.write(b, index/s.powers[j])
+		index %= s.powers[j]
+	}
+}
+
+// errPatternTooLarge 组合数超过 uint64 时无法按序号寻址
+var errPatternTooLarge = fmt.Errorf("模式的组合数超过 %d，请缩小字符集或长度", uint64(math.MaxUint64))
+
+// ParsePattern 解析域名生成模式，语法见 GenerateDomainsFromPattern，config 为 nil 时使用默认配置
+func ParsePattern(pattern string, config *PatternConfig) (*Pattern, error) {
+	if config == nil {
+		config = &PatternConfig{}
+	}
+	if config.Filter != nil {
+		if err := config.Filter.normalize(); err != nil {
+			return nil, err
+		}
+	}
+	parser := &patternParser{src: []rune(pattern), config: config, wordlists: make(map[string][]string)}
+	root, err := parser.parseAlternatives(false)
+	if err != nil {
+		return nil, fmt.Errorf("无效的模式 %s: %w", pattern, err)
+	}
+	if !parser.variable {
+		return nil, fmt.Errorf("无效的模式: %s。模式中没有可变的部分，例如 [a-z]{3}.com", pattern)
+	}
+	return &Pattern{
+		source:   pattern,
+		root:     root,
+		size:     root.size(),
+		allValid: alwaysValid(root),
+		filter:   config.Filter,
+		counts:   make(map[IndexRange]uint64),
+	}, nil
+}
+
+// patternParser 模式的递归下降解析器
+type patternParser struct {
+	src      []rune
+	pos      int
+	variable bool // 是否解析到字符集、集合、词表、多选一或量词
+
+	config    *PatternConfig
+	wordlists map[string][]string // 已加载的词表，同一词表多次出现时只读取一次
+}
+
+// parseAlternatives 解析以 | 分隔的多个选项，inGroup 为 true 时读取到对应的 ) 为止
+func (p *patternParser) parseAlternatives(inGroup bool) (segment, error) {
+	var options []segment
+	// seen 已有选项的原文，只有大小写不同的选项生成的域名相同，只保留第一个
+	seen := make(map[string]struct{})
+	for {
+		start := p.pos
+		option, err := p.parseSequence()
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(string(p.src[start:p.pos]))
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			options = append(options, option)
+		}
+
+		if p.pos >= len(p.src) {
+			if inGroup {
+				return nil, fmt.Errorf("缺少 )")
+			}
+			break
+		}
+		if p.src[p.pos] == ')' {
+			if !inGroup {
+				return nil, fmt.Errorf("位置 %d: 多余的 )", p.pos+1)
+			}
+			p.pos++
+			break
+		}
+		// 剩下的只能是 |
+		p.pos++
+		p.variable = true
+	}
+
+	if len(options) == 1 {
+		return options[0], nil
+	}
+	return newChoiceSegment(options)
+}
+
+// parseSequence 解析依次拼接的分段，遇到 |、) 或结尾时停止
+func (p *patternParser) parseSequence() (segment, error) {
+	var items []segment
+	var literal []rune
+	// quantifiable 最后一个分段是否可以接量词，已经接过量词的分段不能再接
+	quantifiable := false
+
+	flush := func() {
+		if len(literal) > 0 {
+			items = append(items, literalSegment(literal))
+			literal = nil
+		}
+	}
+	push := func(seg segment) {
+		flush()
+		items = append(items, seg)
+		quantifiable = true
+		p.variable = true
+	}
+	// repeat 将量词作用于前一个字符或分段
+	repeat := func(minRepeat, maxRepeat int) error {
+		var elem segment
+		switch {
+		case len(literal) > 0:
+			elem = literalSegment(literal[len(literal)-1])
+			literal = literal[:len(literal)-1]
+			flush()
+		case quantifiable:
+			elem = items[len(items)-1]
+			items = items[:len(items)-1]
+		default:
+			return fmt.Errorf("位置 %d: 量词前面没有可以重复的内容", p.pos+1)
+		}
+		seg, err := newRepeatSegment(elem, minRepeat, maxRepeat)
+		if err != nil {
+			return err
+		}
+		items = append(items, seg)
+		quantifiable = false
+		p.variable = true
+		return nil
+	}
+
+loop:
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; c {
+		case '|', ')':
+			break loop
+
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				return nil, fmt.Errorf("模式以 \\ 结尾")
+			}
+			literal = append(literal, unicode.ToLower(p.src[p.pos+1]))
+			p.pos += 2
+
+		case '[':
+			p.pos++
+			content, err := p.readUntil(']')
+			if err != nil {
+				return nil, err
+			}
+			chars, err := expandCharset(unescapePattern(content))
+			if err != nil {
+				return nil, err
+			}
+			push(newSetSegment(chars))
+
+		case '(':
+			p.pos++
+			seg, err := p.parseAlternatives(true)
+			if err != nil {
+				return nil, err
+			}
+			push(seg)
+
+		case '{':
+			p.pos++
+			content, err := p.readUntil('}')
+			if err != nil {
+				return nil, err
+			}
+			if name, ok := strings.CutPrefix(content, "@"); ok {
+				words, err := p.wordlist(name)
+				if err != nil {
+					return nil, err
+				}
+				push(setSegment(words))
+				continue
+			}
+			// 紧跟在字符或分段后面的 {n} 和 {min,max} 是量词，其余的是集合
+			if m := patternQuantifierRegexp.FindStringSubmatch(content); m != nil && (len(literal) > 0 || quantifiable) {
+				minRepeat, maxRepeat, err := parseRepeat(m[1], m[2])
+				if err != nil {
+					return nil, err
+				}
+				if err := repeat(minRepeat, maxRepeat); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			options := splitPattern(content, ',')
+			for _, option := range options {
+				if option == "" {
+					return nil, fmt.Errorf("集合 {%s} 中有空的选项", content)
+				}
+			}
+			push(newSetSegment(options))
+
+		case '?':
+			if err := repeat(0, 1); err != nil {
+				return nil, err
+			}
+			p.pos++
+
+		case ']', '}':
+			return nil, fmt.Errorf("位置 %d: 多余的 %c，字面字符请使用 \\%c", p.pos+1, c, c)
+
+		default:
+			literal = append(literal, unicode.ToLower(c))
+			p.pos++
+		}
+	}
+	flush()
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return newSequenceSegment(items)
+}
+
+// wordlist 解析词表占位符 {@name:过滤条件} 并返回过滤后的词
+func (p *patternParser) wordlist(placeholder string) ([]string, error) {
+	name, filter, err := parseWordlistPlaceholder(placeholder)
+	if err != nil {
+		return nil, err
+	}
+
+	words, ok := p.wordlists[name]
+	if !ok {
+		if words, err = loadWordlist(name, p.config); err != nil {
+			return nil, err
+		}
+		p.wordlists[name] = words
+	}
+
+	if words = filter.apply(words); len(words) == 0 {
+		return nil, fmt.Errorf("词表 %s 过滤后没有可用的词", name)
+	}
+	return words, nil
+}
+
+// readUntil 读取到未转义的 end 为止，返回其中的内容（保留转义）并跳过 end
+func (p *patternParser) readUntil(end rune) (string, error) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case end:
+			content := string(p.src[start:p.pos])
+			p.pos++
+			return content, nil
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("位置 %d: 缺少 %c", start, end)
+}
+
+// parseRepeat 解析量词的最小和最大重复次数，maxText 为空时与最小次数相同
+func parseRepeat(minText, maxText string) (int, int, error) {
+	minRepeat, err := strconv.Atoi(minText)
+	if err != nil || minRepeat > maxPatternRepeat {
+		return 0, 0, fmt.Errorf("重复次数不能超过 %d: %s", maxPatternRepeat, minText)
+	}
+	maxRepeat := minRepeat
+	if maxText != "" {
+		if maxRepeat, err = strconv.Atoi(maxText); err != nil || maxRepeat > maxPatternRepeat {
+			return 0, 0, fmt.Errorf("重复次数不能超过 %d: %s", maxPatternRepeat, maxText)
+		}
+		if maxRepeat < minRepeat {
+			return 0, 0, fmt.Errorf("无效的重复次数 {%s,%s}: 最大值小于最小值", minText, maxText)
+		}
+	}
+	return minRepeat, maxRepeat, nil
+}
+
+// splitPattern 按未转义的 sep 分割，并去掉转义符
+func splitPattern(s string, sep rune) []string {
+	var parts []string
+	var current strings.Builder
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == sep:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	return append(parts, current.String())
+}
+
+// unescapePattern 去掉转义符
+func unescapePattern(s string) string {
+	return splitPattern(s, -1)[0]
+}
+
+// String 返回原始模式
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Size 返回组合总数（包括会被过滤掉的无效组合），即序号的范围
+func (p *Pattern) Size() uint64 {
+	return p.size
+}
+
+// Count 返回 r 范围内有效组合的精确数量
+// 无法直接证明全部有效（含可能无效的组合或设置了名称过滤）时需要逐个检查整个范围，
+// 结果会被缓存；ctx 取消时返回错误
+func (p *Pattern) Count(ctx context.Context, r IndexRange) (uint64, error) {
+	p.mu.Lock()
+	count, ok := p.counts[r]
+	p.mu.Unlock()
+	if ok {
+		return count, nil
+	}
+
+	count, err := p.countValid(ctx, r.Start, r.End)
+	if err != nil {
+		return 0, err
+	}
+	p.mu.Lock()
+	p.counts[r] = count
+	p.mu.Unlock()
+	return count, nil
+}
+
+// At 返回第 index 个组合（从 0 开始），index 必须小于 Size()，不检查是否有效
+func (p *Pattern) At(index uint64) string {
+	var b strings.Builder
+	p.root.write(&b, index)
+	return b.String()
+}
+
+// candidate 返回第 index 个组合及其是否有效（符合 LDH 规则并满足名称过滤条件）
+func (p *Pattern) candidate(index uint64) (string, bool) {
+	domain := p.At(index)
+	if !p.allValid && !validDomainCandidate(domain) {
+		return domain, false
+	}
+	return domain, p.filter == nil || p.filter.allow(domain)
+}
+
+// Stream 按顺序生成 [start, end) 范围内的有效组合，ctx 取消时停止
+func (p *Pattern) Stream(ctx context.Context, start, end uint64) <-chan string {
+	return p.stream(ctx, start, end, false)
+}
+
+// stream 按顺序生成 [start, end) 范围内的组合
+// keepInvalid 为 true 时无效组合以空字符串占位，使接收方可以按顺序对应序号
+func (p *Pattern) stream(ctx context.Context, start, end uint64, keepInvalid bool) <-chan string {
+	end = min(end, p.size)
+	domainChan := make(chan string, 1024)
+	go func() {
+		defer close(domainChan)
+		for index := start; index < end; index++ {
+			domain, ok := p.candidate(index)
+			if !ok {
+				if !keepInvalid {
+					continue
+				}
+				domain = ""
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case domainChan <- domain:
+			}
+		}
+	}()
+	return domainChan
+}
+
+// IndexRange 组合序号的半开区间 [Start, End)
+type IndexRange struct {
+	Start uint64
+	End   uint64
+}
+
+// Len 区间内的组合数
+func (r IndexRange) Len() uint64 {
+	if r.End <= r.Start {
+		return 0
+	}
+	return r.End - r.Start
+}
+
+// ParseIndexRange 解析 "start:end" 格式的区间，两端都可以省略，例如 "1000:"、":5000"
+func ParseIndexRange(value string, count uint64) (IndexRange, error) {
+	startText, endText, ok := strings.Cut(value, ":")
+	if !ok {
+		return IndexRange{}, fmt.Errorf("无效的范围: %s (需要格式: start:end)", value)
+	}
+
+	r := IndexRange{Start: 0, End: count}
+	var err error
+	if startText = strings.TrimSpace(startText); startText != "" {
+		if r.Start, err = strconv.ParseUint(startText, 10, 64); err != nil {
+			return IndexRange{}, fmt.Errorf("无效的范围起点: %s", startText)
+		}
+	}
+	if endText = strings.TrimSpace(endText); endText != "" {
+		if r.End, err = strconv.ParseUint(endText, 10, 64); err != nil {
+			return IndexRange{}, fmt.Errorf("无效的范围终点: %s", endText)
+		}
+	}
+
+	r.End = min(r.End, count)
+	if r.Start > r.End {
+		return IndexRange{}, fmt.Errorf("无效的范围: %s (起点大于终点或超出组合总数 %d)", value, count)
+	}
+	return r, nil
+}
+
+// Shard 将区间平均分成 n 份，返回第 i 份（从 1 开始），各份连续且互不重叠
+func (r IndexRange) Shard(i, n uint64) IndexRange {
+	length := r.Len()
+	return IndexRange{
+		Start: r.Start + mulDiv(length, i-1, n),
+		End:   r.Start + mulDiv(length, i, n),
+	}
+}
+
+// ParseShard 解析 "i/n" 格式的分片，i 从 1 开始
+func ParseShard(value string) (uint64, uint64, error) {
+	iText, nText, ok := strings.Cut(value, "/")
+	if ok {
+		i, errI := strconv.ParseUint(strings.TrimSpace(iText), 10, 64)
+		n, errN := strconv.ParseUint(strings.TrimSpace(nText), 10, 64)
+		if errI == nil && errN == nil && n > 0 && i >= 1 && i <= n {
+			return i, n, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("无效的分片: %s (需要格式: i/n，1 <= i <= n)", value)
+}
+
+// mulDiv 计算 a*b/c，避免中间结果溢出
+func mulDiv(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	quo, _ := bits.Div64(hi, lo, c)
+	return quo
+}
+
+// mulUint64 计算 a*b，溢出时返回 false
+func mulUint64(a, b uint64) (uint64, bool) {
+	hi, lo := bits.Mul64(a, b)
+	return lo, hi == 0
+}
+
+// addUint64 计算 a+b，溢出时返回 false
+func addUint64(a, b uint64) (uint64, bool) {
+	sum, carry := bits.Add64(a, b, 0)
+	return sum, carry == 0
+}
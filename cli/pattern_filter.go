@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/idna"
+)
+
+// 域名长度限制（RFC 1035），按 A-label 计算
+const (
+	maxLabelLength  = 63
+	maxDomainLength = 253
+)
+
+// validDomainCandidate 判断生成的域名是否符合 LDH 规则:
+// 每个标签只包含字母、数字和连字符，不以连字符开头或结尾，长度不超过 63，总长度不超过 253；
+// 第 3、4 位的连字符保留给 xn-- 等扩展标签，只接受能正确解码的 A-label，
+// 其他位置的连续连字符大多数注册局也不接受
+func validDomainCandidate(domain string) bool {
+	ascii := domain
+	if !isASCII(domain) {
+		var err error
+		if ascii, err = idna.Lookup.ToASCII(domain); err != nil {
+			return false
+		}
+	}
+	if ascii == "" || len(ascii) > maxDomainLength {
+		return false
+	}
+
+	for _, label := range strings.Split(ascii, ".") {
+		if !validLabel(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// validLabel 判断单个 ASCII 标签是否有效
+func validLabel(label string) bool {
+	if label == "" || len(label) > maxLabelLength || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		if !isLDH(label[i]) {
+			return false
+		}
+	}
+
+	if strings.Contains(label, "--") {
+		if !strings.HasPrefix(label, "xn--") || strings.Contains(label[4:], "--") {
+			return false
+		}
+		if _, err := idna.Lookup.ToUnicode(label); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func isLDH(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// isPlain 判断文本是否只包含小写字母和数字，这样的文本放在任何位置都不会产生无效标签
+func isPlain(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentShape 分段生成文本的长度范围，以及是否只包含小写字母和数字
+type segmentShape struct {
+	minLength int
+	maxLength int
+	plain     bool
+}
+
+// shapeOf 计算分段的形状，长度超过 maxDomainLength 时按 maxDomainLength+1 计
+func shapeOf(seg segment) segmentShape {
+	capLength := func(n int) int { return min(n, maxDomainLength+1) }
+
+	switch s := seg.(type) {
+	case literalSegment:
+		return segmentShape{minLength: len(s), maxLength: capLength(len(s)), plain: isPlain(string(s))}
+
+	case setSegment:
+		shape := segmentShape{minLength: maxDomainLength + 1, plain: true}
+		for _, option := range s {
+			shape.minLength = min(shape.minLength, len(option))
+			shape.maxLength = max(shape.maxLength, capLength(len(option)))
+			shape.plain = shape.plain && isPlain(option)
+		}
+		return shape
+
+	case *sequenceSegment:
+		shape := segmentShape{plain: true}
+		for _, item := range s.items {
+			itemShape := shapeOf(item)
+			shape.minLength = capLength(shape.minLength + itemShape.minLength)
+			shape.maxLength = capLength(shape.maxLength + itemShape.maxLength)
+			shape.plain = shape.plain && itemShape.plain
+		}
+		return shape
+
+	case *choiceSegment:
+		shape := segmentShape{minLength: maxDomainLength + 1, plain: true}
+		for _, option := range s.options {
+			optionShape := shapeOf(option)
+			shape.minLength = min(shape.minLength, optionShape.minLength)
+			shape.maxLength = max(shape.maxLength, optionShape.maxLength)
+			shape.plain = shape.plain && optionShape.plain
+		}
+		return shape
+
+	case *repeatSegment:
+		elemShape := shapeOf(s.elem)
+		return segmentShape{
+			minLength: capLength(elemShape.minLength * s.min),
+			maxLength: capLength(elemShape.maxLength * s.max),
+			plain:     elemShape.plain,
+		}
+	}
+	return segmentShape{maxLength: maxDomainLength + 1}
+}
+
+// alwaysValid 判断模式的所有组合是否一定有效，成立时不需要逐个检查就能得到有效组合数
+// 只识别最常见的形式: 顶层由固定文本和只含小写字母、数字且不为空的分段拼接而成，
+// 点只出现在顶层固定文本中且不会产生空标签，总长度不超过一个标签的上限
+func alwaysValid(root segment) bool {
+	items := []segment{root}
+	if seq, ok := root.(*sequenceSegment); ok {
+		items = seq.items
+	}
+
+	total := 0
+	for i, item := range items {
+		shape := shapeOf(item)
+		total += shape.maxLength
+
+		literal, ok := item.(literalSegment)
+		if !ok {
+			if !shape.plain || shape.minLength == 0 {
+				return false
+			}
+			continue
+		}
+
+		text := string(literal)
+		if !isPlain(strings.ReplaceAll(text, ".", "")) || strings.Contains(text, "..") {
+			return false
+		}
+		if i == 0 && strings.HasPrefix(text, ".") || i == len(items)-1 && strings.HasSuffix(text, ".") {
+			return false
+		}
+	}
+	return total <= maxLabelLength
+}
+
+// countValid 统计 [start, end) 范围内的有效组合数
+// 不能直接证明全部有效时逐个检查，按 CPU 数分段并行统计，ctx 取消时返回错误
+func (p *Pattern) countValid(ctx context.Context, start, end uint64) (uint64, error) {
+	end = min(end, p.size)
+	if start >= end {
+		return 0, nil
+	}
+	if p.allValid && p.filter == nil {
+		return end - start, nil
+	}
+
+	workers := uint64(runtime.GOMAXPROCS(0))
+	length := end - start
+	var count atomic.Uint64
+	var wg sync.WaitGroup
+	for i := uint64(0); i < workers; i++ {
+		chunkStart, chunkEnd := start+mulDiv(length, i, workers), start+mulDiv(length, i+1, workers)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var valid uint64
+			for index := chunkStart; index < chunkEnd; index++ {
+				if index%65536 == 0 && ctx.Err() != nil {
+					return
+				}
+				if _, ok := p.candidate(index); ok {
+					valid++
+				}
+			}
+			count.Add(valid)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return count.Load(), nil
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestPatternEnumeration(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		filter  *NameFilter
+		want    []string
+	}{
+		{
+			name:    "repeat range short first",
+			pattern: "[ab]{1,2}.com",
+			want:    []string{"a.com", "b.com", "aa.com", "ab.com", "ba.com", "bb.com"},
+		},
+		{
+			name:    "optional segment",
+			pattern: "get-?app.com",
+			want:    []string{"getapp.com", "get-app.com"},
+		},
+		{
+			name:    "choice with nested syntax",
+			pattern: "(x|y[0-1]).net",
+			want:    []string{"x.net", "y0.net", "y1.net"},
+		},
+		{
+			name:    "suffix set",
+			pattern: "brand.{com,net,org}",
+			want:    []string{"brand.com", "brand.net", "brand.org"},
+		},
+		{
+			name:    "case folded and deduplicated",
+			pattern: "[aAb]x.com",
+			want:    []string{"ax.com", "bx.com"},
+		},
+		{
+			name:    "invalid ldh combinations skipped",
+			pattern: "[a-]{2}.io",
+			want:    []string{"aa.io"},
+		},
+		{
+			name:    "hyphens in third and fourth position skipped",
+			pattern: "ab-[-c]d.com",
+			want:    []string{"ab-cd.com"},
+		},
+		{
+			name:    "name filter",
+			pattern: "[a-c]{2}.com",
+			filter:  &NameFilter{Banned: []string{"b"}},
+			want:    []string{"aa.com", "ac.com", "ca.com", "cc.com"},
+		},
+		{
+			name:    "name filter with repeats and choices",
+			pattern: "(x|[0-1]){2}.dev",
+			filter:  &NameFilter{NoDigitsAfterLetters: true},
+			want:    []string{"xx.dev", "0x.dev", "00.dev", "01.dev", "1x.dev", "10.dev", "11.dev"},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePattern(tt.pattern, &PatternConfig{Filter: tt.filter})
+			if err != nil {
+				t.Fatalf("ParsePattern(%q): %v", tt.pattern, err)
+			}
+
+			var got []string
+			for domain := range p.Stream(ctx, 0, p.Size()) {
+				got = append(got, domain)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Stream = %v, want %v", got, tt.want)
+			}
+
+			count, err := p.Count(ctx, IndexRange{Start: 0, End: p.Size()})
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if count != uint64(len(got)) {
+				t.Errorf("Count = %d, enumerated %d", count, len(got))
+			}
+
+			// 带占位的迭代与按序号取值一一对应
+			var index uint64
+			for domain := range p.stream(ctx, 0, p.Size(), true) {
+				if at, ok := p.candidate(index); ok != (domain != "") || ok && at != domain {
+					t.Errorf("index %d: iterator %q, At %q (valid %v)", index, domain, at, ok)
+				}
+				if domain != "" && p.At(index) != domain {
+					t.Errorf("At(%d) = %q, want %q", index, p.At(index), domain)
+				}
+				index++
+			}
+			if index != p.Size() {
+				t.Errorf("iterated %d indexes, Size = %d", index, p.Size())
+			}
+		})
+	}
+}
+
+func TestPatternCountSubrange(t *testing.T) {
+	p, err := ParsePattern("[a-c-]{1,3}.com", nil)
+	if err != nil {
+		t.Fatalf("ParsePattern: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, r := range []IndexRange{{0, p.Size()}, {0, 1}, {3, 17}, {p.Size() - 5, p.Size()}, {10, 10}} {
+		var want uint64
+		for range p.Stream(ctx, r.Start, r.End) {
+			want++
+		}
+		got, err := p.Count(ctx, r)
+		if err != nil {
+			t.Fatalf("Count(%v): %v", r, err)
+		}
+		if got != want {
+			t.Errorf("Count(%v) = %d, enumerated %d", r, got, want)
+		}
+	}
+}
+
+func TestShardPartition(t *testing.T) {
+	const maxUint64 = ^uint64(0)
+	tests := []struct {
+		name string
+		r    IndexRange
+		n    uint64
+	}{
+		{"even", IndexRange{0, 100}, 4},
+		{"uneven last shard", IndexRange{0, 10}, 3},
+		{"more shards than indexes", IndexRange{5, 8}, 7},
+		{"offset range", IndexRange{1000, 1337}, 16},
+		{"single shard", IndexRange{3, 9}, 1},
+		{"empty range", IndexRange{42, 42}, 5},
+		{"near overflow", IndexRange{0, maxUint64}, 7},
+		{"near overflow offset", IndexRange{maxUint64 - 1000, maxUint64}, 3},
+		{"near overflow many shards", IndexRange{1, maxUint64}, maxUint64 / 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 分片过多时只检查开头和结尾的分片，中间的分片由相邻关系保证
+			shards := make([]uint64, 0, 2000)
+			if tt.n <= 2000 {
+				for i := uint64(1); i <= tt.n; i++ {
+					shards = append(shards, i)
+				}
+			} else {
+				for i := uint64(1); i <= 1000; i++ {
+					shards = append(shards, i, tt.n-1000+i)
+				}
+				slices.Sort(shards)
+			}
+
+			var total, maxLen, minLen uint64
+			minLen = maxUint64
+			for k, i := range shards {
+				shard := tt.r.Shard(i, tt.n)
+				if shard.Start > shard.End {
+					t.Fatalf("shard %d/%d = %v is inverted", i, tt.n, shard)
+				}
+				if shard.Start < tt.r.Start || shard.End > tt.r.End {
+					t.Fatalf("shard %d/%d = %v outside %v", i, tt.n, shard, tt.r)
+				}
+				// 相邻分片首尾相接：没有空隙也没有重叠
+				if k > 0 && shards[k-1] == i-1 {
+					if prev := tt.r.Shard(i-1, tt.n); prev.End != shard.Start {
+						t.Fatalf("shard %d/%d ends at %d, shard %d starts at %d", i-1, tt.n, prev.End, i, shard.Start)
+					}
+				}
+				total += shard.Len()
+				maxLen = max(maxLen, shard.Len())
+				minLen = min(minLen, shard.Len())
+			}
+
+			if first := tt.r.Shard(1, tt.n); first.Start != tt.r.Start {
+				t.Errorf("first shard starts at %d, want %d", first.Start, tt.r.Start)
+			}
+			if last := tt.r.Shard(tt.n, tt.n); last.End != tt.r.End {
+				t.Errorf("last shard ends at %d, want %d", last.End, tt.r.End)
+			}
+			if len(shards) == int(tt.n) && total != tt.r.Len() {
+				t.Errorf("shards cover %d indexes, want %d", total, tt.r.Len())
+			}
+			// 各分片长度最多相差 1
+			if maxLen-minLen > 1 {
+				t.Errorf("shard lengths range from %d to %d", minLen, maxLen)
+			}
+		})
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	const maxUint64 = ^uint64(0)
+	tests := []struct {
+		a, b, c, want uint64
+	}{
+		{10, 2, 3, 6},
+		{maxUint64, maxUint64, maxUint64, maxUint64},
+		{maxUint64, 6, 7, maxUint64 / 7 * 6},
+		{maxUint64, 1, 2, maxUint64 / 2},
+		{1 << 63, 4, 8, 1 << 62},
+		{1 << 63, 3, 2, 3 << 62},
+		{0, maxUint64, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := mulDiv(tt.a, tt.b, tt.c); got != tt.want {
+			t.Errorf("mulDiv(%d, %d, %d) = %d, want %d", tt.a, tt.b, tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// 变体类型
+const (
+	PermutationOmission      = "omission"      // 删除一个字符: example -> exmple
+	PermutationTransposition = "transposition" // 交换相邻字符: example -> exmaple
+	PermutationRepetition    = "repetition"    // 重复一个字符: example -> exaample
+	PermutationKeyboard      = "keyboard"      // 替换或插入键盘上相邻的键: example -> exsmple
+	PermutationBitsquatting  = "bitsquatting"  // 翻转字符的一个比特: example -> dxample
+	PermutationHomoglyph     = "homoglyph"     // 替换为形近字符，包括 IDN: example -> examp1e、exаmple
+	PermutationHyphenation   = "hyphenation"   // 插入连字符: example -> ex-ample
+	PermutationVowelSwap     = "vowel-swap"    // 替换元音: example -> exomple
+	PermutationSubdomain     = "subdomain"     // 插入点变成子域名形式: example -> ex.ample
+	PermutationTLD           = "tld"           // 替换后缀: example.com -> example.net
+)
+
+// PermutationKinds 所有变体类型，按生成顺序排列
+var PermutationKinds = []string{
+	PermutationOmission, PermutationTransposition, PermutationRepetition, PermutationKeyboard,
+	PermutationBitsquatting, PermutationHomoglyph, PermutationHyphenation, PermutationVowelSwap,
+	PermutationSubdomain, PermutationTLD,
+}
+
+// DefaultPermutationTLDs 替换后缀时默认使用的后缀
+var DefaultPermutationTLDs = []string{
+	"com", "net", "org", "info", "biz", "co", "io", "app", "dev", "xyz",
+	"online", "site", "shop", "store", "me", "us", "uk", "cn", "de", "ru",
+}
+
+// keyboardAdjacent QWERTY 键盘上相邻的键
+var keyboardAdjacent = map[rune]string{
+	'1': "2q", '2': "13qw", '3': "24we", '4': "35er", '5': "46rt",
+	'6': "57ty", '7': "68yu", '8': "79ui", '9': "80io", '0': "9op",
+	'q': "12wa", 'w': "23qeas", 'e': "34wrsd", 'r': "45etdf", 't': "56ryfg",
+	'y': "67tugh", 'u': "78yihj", 'i': "89uojk", 'o': "90ipkl", 'p': "0ol",
+	'a': "qwsz", 's': "weadzx", 'd': "ersfxc", 'f': "rtdgcv", 'g': "tyfhvb",
+	'h': "yugjbn", 'j': "uihknm", 'k': "iojlm", 'l': "opk",
+	'z': "asx", 'x': "sdzc", 'c': "dfxv", 'v': "fgcb", 'b': "ghvn", 'n': "hjbm", 'm': "jkn",
+}
+
+// homoglyphs 形近字符，键可以是多个字符（例如 rn 与 m）
+// 非 ASCII 的替换生成 IDN 域名，查询时转换为 punycode
+var homoglyphs = map[string][]string{
+	"a":  {"а", "à", "á", "ä"}, // 西里尔字母 а
+	"b":  {"ь", "ḃ"},
+	"c":  {"с", "ç"}, // 西里尔字母 с
+	"d":  {"cl", "ԁ"},
+	"e":  {"е", "è", "é", "ë"}, // 西里尔字母 е
+	"g":  {"q", "ɡ"},
+	"h":  {"һ"},
+	"i":  {"1", "l", "і", "í", "ï"}, // 西里尔字母 і
+	"j":  {"ј"},
+	"k":  {"κ"},
+	"l":  {"1", "i", "ӏ"},
+	"m":  {"rn", "nn"},
+	"n":  {"ո"},
+	"o":  {"0", "о", "ο", "ö", "ó"}, // 西里尔字母 о、希腊字母 ο
+	"p":  {"р"},
+	"q":  {"g", "ԛ"},
+	"s":  {"ѕ", "ś"},
+	"u":  {"υ", "ü", "ú"},
+	"v":  {"ν"},
+	"w":  {"vv", "ѡ"},
+	"x":  {"х"},
+	"y":  {"у", "ý"},
+	"z":  {"ᴢ", "ż"},
+	"0":  {"o"},
+	"1":  {"l", "i"},
+	"cl": {"d"},
+	"rn": {"m"},
+	"vv": {"w"},
+}
+
+const permutationVowels = "aeiou"
+
+// PermuteConfig 变体生成配置
+type PermuteConfig struct {
+	Kinds []string // 要生成的变体类型，为空时生成全部
+	TLDs  []string // 替换后缀时使用的后缀，为空时使用 DefaultPermutationTLDs
+}
+
+// Permutation 一个变体域名及产生它的变体类型
+type Permutation struct {
+	Domain string
+	Kinds  []string
+}
+
+// Kind 返回逗号分隔的变体类型
+func (p Permutation) Kind() string {
+	return strings.Join(p.Kinds, ",")
+}
+
+// Permute 生成域名的仿冒变体，用于监控品牌被抢注
+// 只对可注册部分的名称做变换，结果按变体类型的顺序排列，不包含原域名和不符合 LDH 规则的域名
+// 变体归约为可注册域名并按 A-label 去重，多种变换得到同一个可注册域名时合并变体类型，
+// 例如 ex.ample.com 与省略字符得到的结果都归约为 ample.com
+func Permute(domain string, config *PermuteConfig) ([]Permutation, error) {
+	if config == nil {
+		config = &PermuteConfig{}
+	}
+	kinds := config.Kinds
+	if len(kinds) == 0 {
+		kinds = PermutationKinds
+	}
+	for _, kind := range kinds {
+		if !slices.Contains(PermutationKinds, kind) {
+			return nil, fmt.Errorf("未知的变体类型: %s (可选: %s)", kind, strings.Join(PermutationKinds, ", "))
+		}
+	}
+	tlds := config.TLDs
+	if len(tlds) == 0 {
+		tlds = DefaultPermutationTLDs
+	}
+
+	name, suffix, err := splitRegistrable(domain)
+	if err != nil {
+		return nil, err
+	}
+	original := permutationKey(name + "." + suffix)
+
+	var perms []Permutation
+	positions := make(map[string]int)
+	add := func(kind, candidate string) {
+		if !validDomainCandidate(candidate) {
+			return
+		}
+		// 子域名形式的变体只有可注册部分能被抢注，例如 ex.ample.com 实际注册的是 ample.com
+		registrableName, registrableSuffix, err := splitRegistrable(candidate)
+		if err != nil {
+			return
+		}
+		if kind == PermutationSubdomain && !resemblesName(registrableName, name) {
+			return
+		}
+		registrable := registrableName + "." + registrableSuffix
+		key := permutationKey(registrable)
+		if key == original {
+			return
+		}
+		if i, ok := positions[key]; ok {
+			if !slices.Contains(perms[i].Kinds, kind) {
+				perms[i].Kinds = append(perms[i].Kinds, kind)
+			}
+			return
+		}
+		positions[key] = len(perms)
+		perms = append(perms, Permutation{Domain: registrable, Kinds: []string{kind}})
+	}
+
+	for _, kind := range PermutationKinds {
+		if !slices.Contains(kinds, kind) {
+			continue
+		}
+		if kind == PermutationTLD {
+			for _, tld := range tlds {
+				add(kind, name+"."+strings.ToLower(strings.Trim(tld, ". ")))
+			}
+			continue
+		}
+		for _, variant := range permuteName(kind, []rune(name)) {
+			add(kind, variant+"."+suffix)
+		}
+	}
+
+	return perms, nil
+}
+
+// splitRegistrable 将域名拆分为可注册部分的名称和公共后缀，例如 www.example.co.uk -> example, co.uk
+func splitRegistrable(domain string) (string, string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	// 公共后缀列表按 Unicode 形式匹配
+	if unicodeDomain, err := idna.Lookup.ToUnicode(domain); err == nil {
+		domain = unicodeDomain
+	}
+
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return "", "", fmt.Errorf("无效的域名 %s: %w", domain, err)
+	}
+	name, suffix, _ := strings.Cut(registrable, ".")
+	return name, suffix, nil
+}
+
+// permutationKey 返回用于去重的 A-label 形式，转换失败时原样返回
+func permutationKey(domain string) string {
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		return ascii
+	}
+	return domain
+}
+
+// resemblesName 判断可注册部分的名称是否仍与原名称相似：编辑距离小于原名称长度的一半
+// 子域名变体在靠后的位置插入点时，剩下的可注册部分（如 exam.ple.com 的 ple）已经看不出原名称
+func resemblesName(candidate, name string) bool {
+	return 2*editDistance([]rune(candidate), []rune(name)) < len([]rune(name))
+}
+
+// editDistance 计算两个字符序列的编辑距离（Levenshtein 距离）
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// permuteName 对名称做一种变换，返回所有结果（可能重复）
+func permuteName(kind string, name []rune) []string {
+	var variants []string
+	replace := func(start, end int, with string) {
+		variants = append(variants, string(name[:start])+with+string(name[end:]))
+	}
+
+	switch kind {
+	case PermutationOmission:
+		if len(name) > 1 {
+			for i := range name {
+				replace(i, i+1, "")
+			}
+		}
+
+	case PermutationTransposition:
+		for i := 0; i+1 < len(name); i++ {
+			if name[i] != name[i+1] {
+				replace(i, i+2, string([]rune{name[i+1], name[i]}))
+			}
+		}
+
+	case PermutationRepetition:
+		for i, c := range name {
+			replace(i, i+1, string([]rune{c, c}))
+		}
+
+	case PermutationKeyboard:
+		for i, c := range name {
+			for _, adjacent := range keyboardAdjacent[c] {
+				replace(i, i+1, string(adjacent))
+				replace(i, i+1, string([]rune{adjacent, c}))
+				replace(i, i+1, string([]rune{c, adjacent}))
+			}
+		}
+
+	case PermutationBitsquatting:
+		for i, c := range name {
+			if c >= 0x80 {
+				continue
+			}
+			for bit := 0; bit < 8; bit++ {
+				if flipped := c ^ (1 << bit); flipped < 0x80 && isLDH(byte(flipped)) && !(flipped >= 'A' && flipped <= 'Z') {
+					replace(i, i+1, string(flipped))
+				}
+			}
+		}
+
+	case PermutationHomoglyph:
+		for i := range name {
+			for length := 1; length <= 2 && i+length <= len(name); length++ {
+				for _, glyph := range homoglyphs[string(name[i:i+length])] {
+					replace(i, i+length, glyph)
+				}
+			}
+		}
+
+	case PermutationHyphenation:
+		for i := 1; i < len(name); i++ {
+			replace(i, i, "-")
+		}
+
+	case PermutationVowelSwap:
+		for i, c := range name {
+			if !strings.ContainsRune(permutationVowels, c) {
+				continue
+			}
+			for _, vowel := range permutationVowels {
+				if vowel != c {
+					replace(i, i+1, string(vowel))
+				}
+			}
+		}
+
+	case PermutationSubdomain:
+		for i := 1; i < len(name); i++ {
+			replace(i, i, ".")
+		}
+	}
+
+	return variants
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPermuteDedupesByRegistrable(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain string
+		kinds  []string
+		want   map[string][]string // 期望包含的域名及其变体类型
+		absent []string            // 不应出现的域名
+	}{
+		{
+			name:   "subdomain merges with omission",
+			domain: "example.com",
+			kinds:  []string{PermutationOmission, PermutationSubdomain},
+			want: map[string][]string{
+				"xample.com": {PermutationOmission, PermutationSubdomain},
+				"ample.com":  {PermutationSubdomain},
+			},
+			absent: []string{"e.xample.com", "ex.ample.com", "ple.com", "e.com", "example.com"},
+		},
+		{
+			name:   "multi-label suffix",
+			domain: "www.example.co.uk",
+			kinds:  []string{PermutationSubdomain},
+			want: map[string][]string{
+				"xample.co.uk": {PermutationSubdomain},
+				"mple.co.uk":   {PermutationSubdomain},
+			},
+			absent: []string{"ple.co.uk", "e.co.uk"},
+		},
+		{
+			name:   "idn homoglyph",
+			domain: "example.com",
+			kinds:  []string{PermutationHomoglyph},
+			want: map[string][]string{
+				"exаmple.com": {PermutationHomoglyph}, // 西里尔字母 а
+				"examp1e.com": {PermutationHomoglyph},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perms, err := Permute(tt.domain, &PermuteConfig{Kinds: tt.kinds})
+			if err != nil {
+				t.Fatalf("Permute: %v", err)
+			}
+
+			seen := make(map[string]bool)
+			byDomain := make(map[string][]string)
+			for _, perm := range perms {
+				key := permutationKey(perm.Domain)
+				if seen[key] {
+					t.Errorf("duplicate registrable domain %s", perm.Domain)
+				}
+				seen[key] = true
+				byDomain[perm.Domain] = perm.Kinds
+			}
+
+			for domain, kinds := range tt.want {
+				got, ok := byDomain[domain]
+				if !ok || !slices.Equal(got, kinds) {
+					t.Errorf("%s kinds = %v (present %v), want %v", domain, got, ok, kinds)
+				}
+			}
+			for _, domain := range tt.absent {
+				if _, ok := byDomain[domain]; ok {
+					t.Errorf("unexpected %s in result", domain)
+				}
+			}
+		})
+	}
+}
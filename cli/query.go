@@ -1,34 +1,80 @@
 package cli
 
 import (
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gois/whois"
 )
 
+// protocolAuto 优先 RDAP、失败时回退 WHOIS 的查询协议
+const protocolAuto = "auto"
+
+// maxRateLimitRequeues 批量查询中单个域名因限速重新排队的最大次数
+const maxRateLimitRequeues = 5
+
+// defaultServerLimitKey ServerLimits 中表示默认限制的键
+const defaultServerLimitKey = "default"
+
 // QueryConfig 查询配置
 type QueryConfig struct {
 	Timeout     time.Duration
 	Proxy       *url.URL
 	OutputFile  string
-	Mode        string // "normal" 或 "simple"
-	MaxRetries  int
+	Mode        string   // "normal" 或 "simple"
+	Format      string   // 输出格式 "text"、"csv"、"json" 或 "ndjson"，为空时按 Mode 选择
+	IncludeRaw  bool     // 结构化输出中包含原始的注册局与注册商响应
+	Columns     []string // CSV 输出的列，为空时使用 DefaultCSVColumns
+	Store       string   // 结果库，格式 "sqlite:path.db"，为空时不保存
+	MaxRetries  int      // 每个域名的最大查询次数
+	RetryBudget int      // 整次运行允许的重试总数，0 表示不限制
 	Concurrency int
 	WhoisServer string
+	Protocol    string // "whois"、"rdap" 或 "auto"
+	RefreshRDAP bool   // 启动时从 IANA 刷新 RDAP 引导数据
+	TLDCache    bool   // 将从 IANA 学习到的 WHOIS 服务器持久化到用户缓存文件
+	TLDFile     string // 合并到内嵌 TLD 映射之上的用户覆盖文件（JSON 或 YAML）
+	// 代理池配置，设置 ProxyFile 后 WHOIS 查询在代理列表中轮换，忽略 Proxy
+	ProxyFile     string
+	ProxyStrategy string // "round-robin" 或 "lru"
+	ProxyCooldown time.Duration
+	// ServerLimits 按 WHOIS 服务器覆盖限速与并发上限，键 "default" 作用于其他所有服务器
+	ServerLimits map[string]whois.ServerLimit
+	// Checkpoint 批量查询的进度文件；Resume 为 true 时跳过已完成的部分，并追加到已有的输出文件
+	Checkpoint string
+	Resume     bool
+	// Score 为每个结果计算名称质量评分；SortByScore 为 true 时结束时按评分从高到低输出
+	Score       bool
+	SortByScore bool
 }
 
 // QueryResult 查询结果
 type QueryResult struct {
-	Domain  string
-	Success bool
-	Result  *whois.QueryResult
-	Error   error
+	Domain        string // 用户输入的原始域名
+	ASCIIDomain   string // 可注册域名的 A-label（punycode）形式
+	UnicodeDomain string // 可注册域名的 Unicode 形式
+	Success       bool
+	Result        *whois.QueryResult
+	Error         error
+	// Attempts 实际发起的查询次数，包括限速重新排队前的尝试
+	Attempts int
+	// QueriedAt 开始查询的时间，Duration 为查询（含重试等待）耗时
+	QueriedAt time.Time
+	Duration  time.Duration
+	// Deferred 表示查询被限速且尚未输出，批量查询会退避后重新排队
+	Deferred bool
 }
 
 // BatchSummary 批量查询统计信息
@@ -40,21 +86,49 @@ type BatchSummary struct {
 	Available  int64
 	Registered int64
 	Unknown    int64
+	// RateLimited 重新排队后仍被限速而失败的域名数
+	RateLimited int64
+	// Requeued 因限速退避后重新排队的次数
+	Requeued int64
+	// Retries 所有域名的重试次数总和
+	Retries int64
+	// Errors 按错误类别统计的失败域名数，例如 timeout、connection_refused、dns
+	Errors map[string]int64
+	// Interrupted 表示批量查询因取消信号提前结束，统计为部分结果
+	Interrupted bool
+	// Skipped 从检查点恢复时跳过的已完成域名数
+	Skipped int64
+	// Err 批量查询无法开始时的错误
+	Err error
+	// Proxies 使用代理池时每个代理的成功与失败次数
+	Proxies []whois.ProxyStats
 }
 
 // HasFailures 是否存在失败
 func (b *BatchSummary) HasFailures() bool {
-	return b != nil && b.Failed > 0
+	return b != nil && (b.Failed > 0 || b.Err != nil)
 }
 
 // CLI 命令行查询工具
 type CLI struct {
-	config   *QueryConfig
-	client   *whois.Client
-	analyzer *whois.Analyzer
-	fileLock sync.Mutex
-	outFile  *os.File
-	logger   *slog.Logger
+	config     *QueryConfig
+	client     *whois.Client
+	rdapClient *whois.RDAPClient
+	analyzer   *whois.Analyzer
+	retries    *retryPolicy
+	fileLock   sync.Mutex
+	outFile    *os.File
+	out        io.Writer // 结果输出目标，可能是 outFile 或标准输出
+	written    int64     // 已写入的结果数
+	csvWriter  *csv.Writer
+	columns    []string // CSV 输出的列
+	store      *resultStore
+	checkpoint *checkpoint
+	// permutations 域名到变体类型的映射，由 SetPermutations 设置，输出时标注每个结果
+	permutations map[string]string
+	// sorted 按评分排序输出时暂存的结果，Close 时写入
+	sorted sortedOutput
+	logger *slog.Logger
 }
 
 // NewCLI 创建新的 CLI 实例
@@ -64,8 +138,39 @@ func NewCLI(config *QueryConfig) (*CLI, error) {
 		return nil, fmt.Errorf("初始化 WHOIS 客户端失败: %w", err)
 	}
 
-	// 初始化 logger
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	// 加载 TLD 缓存与用户覆盖文件
+	if err := ConfigureTLDRegistry(client.Registry(), config); err != nil {
+		return nil, err
+	}
+
+	// 应用 WHOIS 服务器限速配置
+	for server, limit := range config.ServerLimits {
+		if server == defaultServerLimitKey {
+			client.SetDefaultServerLimit(limit)
+		} else {
+			client.SetServerLimit(server, limit)
+		}
+	}
+
+	// 初始化代理池
+	if config.ProxyFile != "" {
+		proxyURLs, err := whois.LoadProxyFile(config.ProxyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载代理列表失败: %w", err)
+		}
+		pool, err := whois.NewProxyPool(proxyURLs, config.ProxyStrategy, config.ProxyCooldown)
+		if err != nil {
+			return nil, fmt.Errorf("初始化代理池失败: %w", err)
+		}
+		client.SetProxyPool(pool)
+	}
+
+	// 初始化 logger，结果写到标准输出时日志改写到标准错误
+	logOutput := io.Writer(os.Stdout)
+	if WritesRecordsToStdout(config.Format, config.OutputFile) {
+		logOutput = os.Stderr
+	}
+	logger := slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
 
@@ -73,109 +178,352 @@ func NewCLI(config *QueryConfig) (*CLI, error) {
 		config:   config,
 		client:   client,
 		analyzer: whois.NewAnalyzer(),
+		retries:  newRetryPolicy(config.RetryBudget),
 		logger:   logger,
 	}
 
-	// 初始化输出文件
-	if config.OutputFile != "" {
-		if err := cli.initOutputFile(); err != nil {
+	// 初始化 RDAP 客户端
+	switch config.Protocol {
+	case "", whois.ProtocolWhois:
+	case whois.ProtocolRDAP, protocolAuto:
+		rdapClient, err := whois.NewRDAPClient(config.Timeout, config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("初始化 RDAP 客户端失败: %w", err)
+		}
+		// RDAP 请求同样经过代理池和服务器限速
+		if pool := client.ProxyPool(); pool != nil {
+			rdapClient.SetProxyPool(pool)
+		}
+		rdapClient.ShareLimits(client)
+		if config.RefreshRDAP {
+			if err := rdapClient.RefreshBootstrap(context.Background()); err != nil {
+				logger.Warn("刷新 RDAP 引导数据失败，继续使用本地数据", "error", err)
+			}
+		}
+		cli.rdapClient = rdapClient
+	default:
+		return nil, fmt.Errorf("无效的查询协议: %s (可选: whois, rdap, auto)", config.Protocol)
+	}
+
+	// 初始化结果库
+	if config.Store != "" {
+		store, err := openResultStore(config.Store)
+		if err != nil {
+			return nil, err
+		}
+		cli.store = store
+	}
+
+	// 初始化检查点
+	if config.Checkpoint != "" {
+		checkpoint, err := openCheckpoint(config.Checkpoint, config.Resume)
+		if err != nil {
+			cli.Close()
 			return nil, err
 		}
+		checkpoint.flushResults = cli.flushResults
+		cli.checkpoint = checkpoint
+	}
+
+	// 初始化输出
+	if err := cli.initOutput(); err != nil {
+		cli.Close()
+		return nil, err
 	}
 
 	return cli, nil
 }
 
-// Close 关闭 CLI 资源
+// Close 关闭 CLI 资源，可重复调用
 func (c *CLI) Close() error {
-	if c.outFile != nil {
-		return c.outFile.Close()
-	}
-	return nil
-}
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
 
-// initOutputFile 初始化输出文件
-func (c *CLI) initOutputFile() error {
-	file, err := os.Create(c.config.OutputFile)
-	if err != nil {
-		return fmt.Errorf("创建输出文件失败: %w", err)
+	var err error
+	if c.store != nil {
+		err = c.store.close()
+		c.store = nil
 	}
-
-	c.outFile = file
-
-	// 写入文件头
-	if c.config.Mode == "simple" {
-		_, err = fmt.Fprintf(file, "domain,status\n")
-	} else {
-		_, err = fmt.Fprintf(file, "# WHOIS 查询结果\n")
-		_, err = fmt.Fprintf(file, "# 查询时间: %s\n", time.Now().Format(time.RFC3339))
-		_, err = fmt.Fprintf(file, "# 模式: %s\n", c.config.Mode)
-		_, err = fmt.Fprintf(file, "%s\n\n", strings.Repeat("=", 80))
+	if c.checkpoint != nil {
+		if closeErr := c.checkpoint.close(); err == nil {
+			err = closeErr
+		}
+		c.checkpoint = nil
 	}
 
+	if c.out == nil {
+		return err
+	}
+	if sortErr := c.writeSortedLocked(); err == nil {
+		err = sortErr
+	}
+	if footerErr := c.writeFooter(); err == nil {
+		err = footerErr
+	}
+	if c.outFile != nil {
+		if closeErr := c.outFile.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	c.out = nil
+	c.outFile = nil
 	return err
 }
 
 // QuerySingleDomain 查询单个域名
-func (c *CLI) QuerySingleDomain(domain string) *QueryResult {
+// ctx 取消时放弃剩余重试，结果不会写入输出文件
+func (c *CLI) QuerySingleDomain(ctx context.Context, domain string) *QueryResult {
+	return c.querySingle(ctx, batchItem{domain: domain}, false)
+}
+
+// querySingle 查询单个域名
+// 按错误分类决定是否重试：无效域名等永久性错误立即失败，其他错误指数退避后重试
+// deferRateLimit 为 true 时遇到限速立即返回且不输出结果，由批量查询退避后重新排队
+func (c *CLI) querySingle(ctx context.Context, item batchItem, deferRateLimit bool) *QueryResult {
+	domain := item.domain
 	c.logger.Info("正在查询域名", "domain", domain)
 
+	queryResult := &QueryResult{Domain: domain, Attempts: item.attempts, QueriedAt: time.Now()}
+
+	// 先做 IDNA 转换与校验，无效域名不需要重试
+	asciiDomain, unicodeDomain, err := whois.NormalizeDomain(domain)
+	if err != nil {
+		c.logger.Error("域名无效", "domain", domain, "error", err)
+		queryResult.Error = err
+		queryResult.Duration = time.Since(queryResult.QueriedAt)
+		c.writeResult(queryResult)
+		return queryResult
+	}
+	queryResult.ASCIIDomain = asciiDomain
+	queryResult.UnicodeDomain = unicodeDomain
+
 	var lastErr error
 	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
-		result, err := c.client.Fetch(domain, c.config.WhoisServer)
+		queryResult.Attempts++
+		result, err := c.fetch(ctx, domain)
 		if err == nil {
 			// 查询成功
-			c.printResult(domain, result)
-			c.writeResult(domain, result, nil)
+			queryResult.Success = true
+			queryResult.Result = result
+			queryResult.Duration = time.Since(queryResult.QueriedAt)
+			c.printResult(queryResult)
+			c.writeResult(queryResult)
 
-			return &QueryResult{
-				Domain:  domain,
-				Success: true,
-				Result:  result,
-			}
+			return queryResult
 		}
 
 		lastErr = err
-		if attempt < c.config.MaxRetries-1 {
-			c.logger.Warn("查询失败，正在重试",
-				"domain", domain,
-				"attempt", attempt+1,
-				"max_retries", c.config.MaxRetries,
-				"error", err)
-			time.Sleep(time.Second * 2)
+		if ctx.Err() != nil {
+			return c.cancelledResult(ctx, domain)
+		}
+
+		class := classifyError(err)
+		if class == errorClassRateLimited && deferRateLimit && c.retries.allow(class) {
+			queryResult.Error = err
+			queryResult.Deferred = true
+			return queryResult
+		}
+
+		if attempt == c.config.MaxRetries-1 {
+			break
+		}
+		if !c.retries.allow(class) {
+			if class != errorClassPermanent {
+				c.logger.Warn("重试次数已用完，放弃重试", "domain", domain, "retry_budget", c.config.RetryBudget)
+			}
+			break
+		}
+
+		delay := c.retries.delay(class, attempt, retryAfter(err))
+		c.logger.Warn("查询失败，正在重试",
+			"domain", domain,
+			"attempt", attempt+1,
+			"max_retries", c.config.MaxRetries,
+			"error_class", class,
+			"delay", delay,
+			"error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return c.cancelledResult(ctx, domain)
+		case <-timer.C:
 		}
 	}
 
 	// 所有重试都失败
-	c.logger.Error("域名查询失败", "domain", domain, "error", lastErr)
-	c.writeResult(domain, nil, lastErr)
+	c.logger.Error("域名查询失败", "domain", domain, "attempts", queryResult.Attempts, "error", lastErr)
+	queryResult.Error = lastErr
+	queryResult.Duration = time.Since(queryResult.QueriedAt)
+	c.writeResult(queryResult)
+
+	return queryResult
+}
 
+// fetch 按配置的协议查询域名
+// auto 模式优先使用 RDAP，RDAP 不可用或失败时回退到 WHOIS
+func (c *CLI) fetch(ctx context.Context, domain string) (*whois.QueryResult, error) {
+	switch c.config.Protocol {
+	case whois.ProtocolRDAP:
+		return c.rdapClient.Fetch(ctx, domain)
+	case protocolAuto:
+		// 指定了 WHOIS 服务器时直接使用 WHOIS
+		if c.config.WhoisServer == "" {
+			result, err := c.rdapClient.Fetch(ctx, domain)
+			if err == nil || ctx.Err() != nil {
+				return result, err
+			}
+			var badDomain *whois.BadDomainError
+			if errors.As(err, &badDomain) {
+				return nil, err
+			}
+			var noServer *whois.NoRDAPServerFoundError
+			if !errors.As(err, &noServer) {
+				c.logger.Warn("RDAP 查询失败，回退到 WHOIS", "domain", domain, "error", err)
+			}
+		}
+	}
+	return c.client.FetchContext(ctx, domain, c.config.WhoisServer)
+}
+
+// isCancelled 判断查询是否因 ctx 取消而中止
+func isCancelled(ctx context.Context, result *QueryResult) bool {
+	return ctx.Err() != nil && errors.Is(result.Error, ctx.Err())
+}
+
+// cancelledResult 构造因取消而中止的查询结果
+func (c *CLI) cancelledResult(ctx context.Context, domain string) *QueryResult {
 	return &QueryResult{
 		Domain:  domain,
 		Success: false,
-		Error:   lastErr,
+		Error:   ctx.Err(),
+	}
+}
+
+// SetPermutations 设置批量查询中各域名的变体类型，需要在查询开始前调用
+func (c *CLI) SetPermutations(perms []Permutation) {
+	c.permutations = make(map[string]string, len(perms))
+	for _, perm := range perms {
+		c.permutations[perm.Domain] = perm.Kind()
 	}
 }
 
 // QueryBatchDomains 批量查询域名（使用内存中的域名列表）
-func (c *CLI) QueryBatchDomains(domains []string) *BatchSummary {
+func (c *CLI) QueryBatchDomains(ctx context.Context, domains []string) *BatchSummary {
 	c.logger.Info("开始批量查询",
 		"total_domains", len(domains),
 		"concurrency", c.config.Concurrency)
 
 	domainChan := make(chan string, c.channelBufferSize())
 	go func() {
+		defer close(domainChan)
 		for _, domain := range domains {
-			domainChan <- domain
+			select {
+			case <-ctx.Done():
+				return
+			case domainChan <- domain:
+			}
 		}
-		close(domainChan)
 	}()
 
-	return c.QueryBatchDomainsStream(domainChan, int64(len(domains)))
+	return c.queryBatch(ctx, batchSource{
+		total:          int64(len(domains)),
+		checkpointMode: checkpointByDomain,
+		open:           func(uint64) <-chan string { return domainChan },
+	})
 }
 
 // QueryBatchDomainsStream 批量查询域名（使用流式域名来源）
-func (c *CLI) QueryBatchDomainsStream(domains <-chan string, totalHint int64) *BatchSummary {
+// ctx 取消后不再向工作协程分发新域名，等待进行中的查询退出后刷新输出文件并返回部分统计
+// 域名流的顺序必须是确定的，检查点按序号记录连续完成的位置
+func (c *CLI) QueryBatchDomainsStream(ctx context.Context, domains <-chan string, totalHint int64) *BatchSummary {
+	return c.queryBatch(ctx, batchSource{
+		total:          totalHint,
+		checkpointMode: checkpointByIndex,
+		open:           func(uint64) <-chan string { return domains },
+	})
+}
+
+// QueryPattern 批量查询模式在 [r.Start, r.End) 范围内的有效组合
+// 从检查点恢复时直接从第一个未完成的序号开始生成，不需要重新枚举已完成的部分
+func (c *CLI) QueryPattern(ctx context.Context, p *Pattern, r IndexRange) *BatchSummary {
+	total, err := p.Count(ctx, r)
+	if err != nil {
+		return &BatchSummary{Err: err}
+	}
+
+	return c.queryBatch(ctx, batchSource{
+		total:          int64(min(total, math.MaxInt64)),
+		checkpointMode: checkpointByIndex,
+		first:          r.Start,
+		last:           r.End,
+		seekable:       true,
+		open:           func(start uint64) <-chan string { return p.stream(ctx, start, r.End, true) },
+		count: func(start, end uint64) int64 {
+			count, _ := p.Count(ctx, IndexRange{Start: start, End: end})
+			return int64(min(count, math.MaxInt64))
+		},
+	})
+}
+
+// QueryPatternSample 从模式在 r 范围内的有效组合中按种子随机抽取 n 个不重复的组合查询
+// 用于在完整扫描之前估计可用域名的比例；从检查点恢复时必须使用相同的种子
+func (c *CLI) QueryPatternSample(ctx context.Context, p *Pattern, r IndexRange, n uint64, seed int64) *BatchSummary {
+	total, err := p.Count(ctx, r)
+	if err != nil {
+		return &BatchSummary{Err: err}
+	}
+
+	return c.queryBatch(ctx, batchSource{
+		total:          int64(min(n, total, math.MaxInt64)),
+		checkpointMode: checkpointByIndex,
+		open:           func(uint64) <-chan string { return p.sample(ctx, r, n, seed) },
+	})
+}
+
+// batchSource 批量查询的域名来源
+type batchSource struct {
+	total          int64  // 域名总数，未知时为 -1
+	checkpointMode string // 检查点按域名还是按序号记录进度
+	// first、last 域名流中第一个和最后一个之后的序号，last 为 0 时不限制
+	first, last uint64
+	// seekable 表示 open 可以从任意序号开始，否则只能从 first 开始并由调用方跳过
+	seekable bool
+	// open 从序号 start 开始打开域名流，流中的空字符串表示该序号被过滤掉，不需要查询
+	open func(start uint64) <-chan string
+	// count 统计 [start, end) 范围内需要查询的域名数，为 nil 时按序号个数计算
+	count func(start, end uint64) int64
+}
+
+// queryBatch 并发查询域名流
+func (c *CLI) queryBatch(ctx context.Context, src batchSource) *BatchSummary {
+	summary := &BatchSummary{Requested: src.total}
+	checkpointMode := src.checkpointMode
+
+	// 从检查点恢复时跳过已完成的部分
+	resumeIndex := src.first
+	if c.checkpoint != nil {
+		if err := c.checkpoint.begin(checkpointMode, src.total, src.first, src.last); err != nil {
+			c.logger.Error("无法使用检查点", "error", err)
+			summary.Err = err
+			return summary
+		}
+		resumeIndex = c.checkpoint.nextIndex()
+	}
+	var skipped atomic.Int64
+
+	streamStart := src.first
+	if src.seekable {
+		streamStart = resumeIndex
+		if src.count != nil {
+			skipped.Store(src.count(src.first, resumeIndex))
+		} else {
+			skipped.Store(int64(resumeIndex - src.first))
+		}
+	}
+	domains := src.open(streamStart)
+
 	workerCount := c.config.Concurrency
 	if workerCount <= 0 {
 		workerCount = 1
@@ -184,13 +532,91 @@ func (c *CLI) QueryBatchDomainsStream(domains <-chan string, totalHint int64) *B
 	resultChan := make(chan *QueryResult, workerCount*2)
 	var workerWG sync.WaitGroup
 
+	// pending 统计已分发但尚未得出最终结果的域名（包括等待重新排队的），归零后才能关闭 input
+	input := make(chan batchItem)
+	var pending sync.WaitGroup
+	var requeued atomic.Int64
+
+	go func() {
+		defer func() {
+			pending.Wait()
+			close(input)
+		}()
+		for index := streamStart; ; index++ {
+			var domain string
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case domain, ok = <-domains:
+				if !ok {
+					return
+				}
+			}
+
+			if domain == "" {
+				// 被过滤掉的序号直接记为完成，否则检查点的连续完成位置无法前进
+				if c.checkpoint != nil && checkpointMode == checkpointByIndex && index >= resumeIndex {
+					if err := c.checkpoint.markDone(batchItem{index: index}); err != nil {
+						c.logger.Error("写入检查点失败", "index", index, "error", err)
+					}
+				}
+				continue
+			}
+
+			if c.checkpoint != nil {
+				if checkpointMode == checkpointByIndex && index < resumeIndex || checkpointMode == checkpointByDomain && c.checkpoint.isDone(domain) {
+					skipped.Add(1)
+					continue
+				}
+			}
+
+			pending.Add(1)
+			select {
+			case <-ctx.Done():
+				pending.Done()
+				return
+			case input <- batchItem{domain: domain, index: index}:
+			}
+		}
+	}()
+
 	// 启动工作协程
 	for i := 0; i < workerCount; i++ {
 		workerWG.Add(1)
 		go func() {
 			defer workerWG.Done()
-			for domain := range domains {
-				resultChan <- c.QuerySingleDomain(domain)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item, ok := <-input:
+					if !ok {
+						return
+					}
+					// select 在两者同时就绪时随机选择，这里再确认一次
+					if ctx.Err() != nil {
+						pending.Done()
+						return
+					}
+
+					result := c.querySingle(ctx, item, item.requeues < maxRateLimitRequeues)
+					if result.Deferred {
+						requeued.Add(1)
+						item.attempts = result.Attempts
+						go c.requeue(ctx, input, &pending, item, result.Error)
+						continue
+					}
+
+					if c.checkpoint != nil && !isCancelled(ctx, result) {
+						if err := c.checkpoint.markDone(item); err != nil {
+							c.logger.Error("写入检查点失败", "domain", item.domain, "error", err)
+						}
+					}
+
+					pending.Done()
+					resultChan <- result
+				}
 			}
 		}()
 	}
@@ -201,60 +627,138 @@ func (c *CLI) QueryBatchDomainsStream(domains <-chan string, totalHint int64) *B
 		close(resultChan)
 	}()
 
-	summary := &BatchSummary{Requested: totalHint}
 	progressInterval := int64(100)
-	if totalHint > 0 {
+	if src.total > 0 {
 		// 根据总量调节进度日志频率，防止刷屏
 		switch {
-		case totalHint >= 1_000_000:
+		case src.total >= 1_000_000:
 			progressInterval = 10_000
-		case totalHint >= 100_000:
+		case src.total >= 100_000:
 			progressInterval = 1_000
-		case totalHint >= 10_000:
+		case src.total >= 10_000:
 			progressInterval = 500
 		}
 	}
 
 	for result := range resultChan {
+		// 被取消的查询没有真正完成，不计入统计
+		if isCancelled(ctx, result) {
+			continue
+		}
+
 		summary.Processed++
+		if result.Attempts > 1 {
+			summary.Retries += int64(result.Attempts - 1)
+		}
 		if result.Success {
 			summary.Success++
-			if c.config.Mode == "simple" && result.Result != nil {
+			// 所有模式都按状态统计，抽样估计等依赖可用与已注册的数量
+			if result.Result != nil {
 				status := c.analyzer.GetDomainStatus(result.Result)
 				switch status {
-				case "available":
+				case whois.StatusAvailable:
 					summary.Available++
-				case "registered":
+				case whois.StatusRegistered:
 					summary.Registered++
-				case "unknown":
+				case whois.StatusUnknown:
 					summary.Unknown++
+				case whois.StatusRateLimited:
+					summary.RateLimited++
 				}
 			}
 		} else {
 			summary.Failed++
+			category := errorCategory(result.Error)
+			if category == errorCategoryRateLimited {
+				summary.RateLimited++
+			}
+			if summary.Errors == nil {
+				summary.Errors = make(map[string]int64)
+			}
+			summary.Errors[category]++
 		}
 
 		// 释放结果占用的内存
 		result.Result = nil
 
 		if progressInterval <= 1 || summary.Processed%progressInterval == 0 {
-			attrs := []any{"completed", summary.Processed}
-			if totalHint > 0 {
-				attrs = append(attrs, "total", totalHint)
+			attrs := []any{"completed", summary.Processed + skipped.Load()}
+			if src.total > 0 {
+				attrs = append(attrs, "total", src.total)
 			}
 			c.logger.Info("查询进度", attrs...)
 		}
 	}
 
+	summary.Skipped = skipped.Load()
 	if summary.Requested < 0 {
-		summary.Requested = summary.Processed
+		summary.Requested = summary.Processed + summary.Skipped
 	}
+	summary.Requeued = requeued.Load()
+
+	if ctx.Err() != nil {
+		summary.Interrupted = true
+		c.logger.Warn("批量查询已中断，输出部分结果")
+	}
+
+	if err := c.syncOutputFile(); err != nil {
+		c.logger.Error("刷新输出文件失败", "error", err)
+	}
+
+	summary.Proxies = c.client.ProxyStats()
 
 	c.printStatistics(summary)
 
 	return summary
 }
 
+// batchItem 批量查询中的一个待查询域名
+type batchItem struct {
+	domain   string
+	index    uint64 // 在域名流中的序号
+	requeues int
+	attempts int // 重新排队前已经发起的查询次数
+}
+
+// requeue 被限速的域名退避后重新放回队列
+func (c *CLI) requeue(ctx context.Context, input chan<- batchItem, pending *sync.WaitGroup, item batchItem, err error) {
+	delay := c.retries.delay(errorClassRateLimited, item.requeues, retryAfter(err))
+
+	c.logger.Warn("查询被限速，稍后重新排队",
+		"domain", item.domain,
+		"requeues", item.requeues+1,
+		"delay", delay,
+		"error", err)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		pending.Done()
+		return
+	case <-timer.C:
+	}
+
+	item.requeues++
+	select {
+	case <-ctx.Done():
+		pending.Done()
+	case input <- item:
+	}
+}
+
+// syncOutputFile 将输出文件内容刷入磁盘
+func (c *CLI) syncOutputFile() error {
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+
+	if c.outFile == nil {
+		return nil
+	}
+	return c.outFile.Sync()
+}
+
 func (c *CLI) channelBufferSize() int {
 	bufferSize := c.config.Concurrency * 2
 	if bufferSize < 1 {
@@ -267,24 +771,42 @@ func (c *CLI) channelBufferSize() int {
 }
 
 // printResult 打印查询结果
-func (c *CLI) printResult(domain string, result *whois.QueryResult) {
+func (c *CLI) printResult(queryResult *QueryResult) {
+	domain := queryResult.Domain
+	result := queryResult.Result
+
 	if c.config.Mode == "simple" {
 		statusCode := c.analyzer.GetDomainStatus(result)
 		var status string
 		switch statusCode {
-		case "available":
+		case whois.StatusAvailable:
 			status = "可用"
-		case "registered":
+		case whois.StatusRegistered:
 			status = "已注册"
-		case "unknown":
+		case whois.StatusUnknown:
 			status = "未知"
+		case whois.StatusRateLimited:
+			status = "被限速"
 		default:
 			status = "未知"
 		}
-		c.logger.Info("查询结果", "domain", domain, "status", status)
-	} else {
+		attrs := []any{"domain", domain, "status", status}
+		if queryResult.ASCIIDomain != domain {
+			attrs = append(attrs, "ascii", queryResult.ASCIIDomain)
+		}
+		if queryResult.Attempts > 1 {
+			attrs = append(attrs, "attempts", queryResult.Attempts)
+		}
+		if kind := c.permutations[domain]; kind != "" {
+			attrs = append(attrs, "permutation", kind)
+		}
+		c.logger.Info("查询结果", attrs...)
+	} else if c.out != os.Stdout {
 		fmt.Println(strings.Repeat("=", 80))
 		fmt.Printf("域名: %s\n", domain)
+		if queryResult.ASCIIDomain != domain {
+			fmt.Printf("查询域名: %s (%s)\n", queryResult.ASCIIDomain, queryResult.UnicodeDomain)
+		}
 		fmt.Println(strings.Repeat("-", 80))
 
 		if result.RegistrarResult != "" {
@@ -301,50 +823,6 @@ func (c *CLI) printResult(domain string, result *whois.QueryResult) {
 	}
 }
 
-// writeResult 将结果写入文件
-func (c *CLI) writeResult(domain string, result *whois.QueryResult, err error) {
-	if c.outFile == nil {
-		return
-	}
-
-	c.fileLock.Lock()
-	defer c.fileLock.Unlock()
-
-	if c.config.Mode == "simple" {
-		status := "unknown"
-		if err == nil && result != nil {
-			status = c.analyzer.GetDomainStatus(result)
-		}
-		fmt.Fprintf(c.outFile, "%s,%s\n", domain, status)
-	} else {
-		fmt.Fprintf(c.outFile, "\n%s\n", strings.Repeat("=", 80))
-		fmt.Fprintf(c.outFile, "域名: %s\n", domain)
-		fmt.Fprintf(c.outFile, "查询时间: %s\n", time.Now().Format(time.RFC3339))
-
-		if err != nil {
-			fmt.Fprintf(c.outFile, "错误: %v\n", err)
-		} else if result != nil {
-			fmt.Fprintf(c.outFile, "\n注册商 WHOIS 服务器结果:\n")
-			fmt.Fprintf(c.outFile, "%s\n", strings.Repeat("-", 80))
-			if result.RegistrarResult != "" {
-				fmt.Fprintf(c.outFile, "%s\n", result.RegistrarResult)
-			} else {
-				fmt.Fprintf(c.outFile, "无数据\n")
-			}
-
-			fmt.Fprintf(c.outFile, "\n\n注册局 WHOIS 服务器结果:\n")
-			fmt.Fprintf(c.outFile, "%s\n", strings.Repeat("-", 80))
-			if result.RegistryResult != "" {
-				fmt.Fprintf(c.outFile, "%s\n", result.RegistryResult)
-			} else {
-				fmt.Fprintf(c.outFile, "无数据\n")
-			}
-		}
-
-		fmt.Fprintf(c.outFile, "\n%s\n", strings.Repeat("=", 80))
-	}
-}
-
 // printStatistics 打印统计信息
 func (c *CLI) printStatistics(summary *BatchSummary) {
 	if summary == nil {
@@ -358,13 +836,49 @@ func (c *CLI) printStatistics(summary *BatchSummary) {
 		"failed", summary.Failed,
 	}
 
-	if c.config.Mode == "simple" {
-		attrs = append(attrs,
-			"available", summary.Available,
-			"registered", summary.Registered,
-			"unknown", summary.Unknown,
-		)
+	if summary.Retries > 0 {
+		attrs = append(attrs, "retries", summary.Retries)
+	}
+
+	if summary.RateLimited > 0 || summary.Requeued > 0 {
+		attrs = append(attrs, "rate_limited", summary.RateLimited, "requeued", summary.Requeued)
+	}
+
+	if summary.Skipped > 0 {
+		attrs = append(attrs, "skipped", summary.Skipped)
+	}
+
+	if summary.Interrupted {
+		attrs = append(attrs, "interrupted", true)
 	}
 
+	attrs = append(attrs,
+		"available", summary.Available,
+		"registered", summary.Registered,
+		"unknown", summary.Unknown,
+	)
+
 	c.logger.Info("批量查询完成", attrs...)
+
+	if len(summary.Errors) > 0 {
+		categories := make([]string, 0, len(summary.Errors))
+		for category := range summary.Errors {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+
+		errorAttrs := make([]any, 0, len(categories)*2)
+		for _, category := range categories {
+			errorAttrs = append(errorAttrs, category, summary.Errors[category])
+		}
+		c.logger.Info("错误统计", errorAttrs...)
+	}
+
+	for _, stats := range summary.Proxies {
+		c.logger.Info("代理统计",
+			"proxy", stats.Proxy,
+			"success", stats.Success,
+			"failed", stats.Failed,
+			"unhealthy", stats.Unhealthy)
+	}
 }
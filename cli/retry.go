@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"gois/whois"
+)
+
+// errorClass 查询错误的分类，决定是否重试以及退避时间
+type errorClass int
+
+const (
+	errorClassPermanent   errorClass = iota // 重试也无法成功，例如无效域名、没有 WHOIS 服务器
+	errorClassTransient                     // 暂时性的网络错误
+	errorClassTimeout                       // 连接或读取超时
+	errorClassRateLimited                   // 被服务器限速或拒绝
+)
+
+// String 返回错误分类名称
+func (c errorClass) String() string {
+	switch c {
+	case errorClassPermanent:
+		return "permanent"
+	case errorClassTimeout:
+		return "timeout"
+	case errorClassRateLimited:
+		return "rate_limited"
+	default:
+		return "transient"
+	}
+}
+
+// classifyError 对查询错误分类
+func classifyError(err error) errorClass {
+	var badDomain *whois.BadDomainError
+	var noWhoisServer *whois.NoWhoisServerFoundError
+	var noRDAPServer *whois.NoRDAPServerFoundError
+	var rateLimited *whois.RateLimitedError
+	var rdapErr *whois.RDAPError
+	var timeoutErr *whois.SocketTimeoutError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &badDomain), errors.As(err, &noWhoisServer), errors.As(err, &noRDAPServer):
+		return errorClassPermanent
+	case whois.IsResponseTooLarge(err):
+		// 同一服务器再次查询仍然会超过大小限制
+		return errorClassPermanent
+	case errors.As(err, &rateLimited):
+		return errorClassRateLimited
+	case errors.As(err, &rdapErr) && rdapErr.StatusCode >= 400 && rdapErr.StatusCode < 500:
+		return errorClassPermanent
+	case errors.As(err, &timeoutErr), errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return errorClassTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errorClassTimeout
+	}
+	return errorClassTransient
+}
+
+// 各类错误的退避参数
+var backoffByClass = map[errorClass]struct{ base, max time.Duration }{
+	errorClassTransient:   {base: time.Second, max: 30 * time.Second},
+	errorClassTimeout:     {base: 2 * time.Second, max: time.Minute},
+	errorClassRateLimited: {base: 10 * time.Second, max: 2 * time.Minute},
+}
+
+// retryPolicy 重试策略：按错误分类做带抖动的指数退避，并限制整次运行的重试总数
+type retryPolicy struct {
+	// budget 剩余可用的重试次数，为 nil 时不限制
+	budget *atomic.Int64
+}
+
+// newRetryPolicy 创建重试策略，budget <= 0 表示不限制重试总数
+func newRetryPolicy(budget int) *retryPolicy {
+	policy := &retryPolicy{}
+	if budget > 0 {
+		policy.budget = &atomic.Int64{}
+		policy.budget.Store(int64(budget))
+	}
+	return policy
+}
+
+// allow 判断该错误是否还能重试，可以重试时消耗一次重试预算
+func (p *retryPolicy) allow(class errorClass) bool {
+	if class == errorClassPermanent {
+		return false
+	}
+	if p.budget == nil {
+		return true
+	}
+	return p.budget.Add(-1) >= 0
+}
+
+// delay 计算第 attempt 次（从 0 开始）失败后的等待时间
+// 指数增长并加入抖动，取 [d/2, d) 之间的随机值，避免并发的工作协程同时重试；服务器给出 Retry-After 时优先使用
+func (p *retryPolicy) delay(class errorClass, attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	params, ok := backoffByClass[class]
+	if !ok {
+		params = backoffByClass[errorClassTransient]
+	}
+
+	d := params.base << attempt
+	if d <= 0 || d > params.max {
+		d = params.max
+	}
+
+	half := d / 2
+	return half + rand.N(d-half)
+}
+
+// retryAfter 提取服务器建议的等待时间
+func retryAfter(err error) time.Duration {
+	var rateLimited *whois.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"math/bits"
+)
+
+// feistelRounds Feistel 网络的轮数，4 轮以上的伪随机排列与随机排列已经难以区分
+const feistelRounds = 6
+
+// indexPermutation [0, n) 上由种子决定的伪随机排列
+// 用 Feistel 网络在不小于 n 的 2 的偶数次幂上构造双射，超出 n 的值继续迭代（cycle walking），
+// 因此可以按位置直接计算，不需要保存整个排列
+type indexPermutation struct {
+	n        uint64
+	halfBits uint
+	mask     uint64
+	keys     [feistelRounds]uint64
+}
+
+func newIndexPermutation(n uint64, seed int64) *indexPermutation {
+	totalBits := uint(bits.Len64(n - 1))
+	if n <= 1 {
+		totalBits = 0
+	}
+	totalBits = max(2, totalBits+totalBits%2)
+
+	p := &indexPermutation{
+		n:        n,
+		halfBits: totalBits / 2,
+		mask:     1<<(totalBits/2) - 1,
+	}
+	state := uint64(seed)
+	for i := range p.keys {
+		state += 0x9e3779b97f4a7c15
+		p.keys[i] = splitMix64(state)
+	}
+	return p
+}
+
+// at 返回排列中第 i 个位置的值，i 必须小于 n
+func (p *indexPermutation) at(i uint64) uint64 {
+	x := p.encrypt(i)
+	for x >= p.n {
+		x = p.encrypt(x)
+	}
+	return x
+}
+
+func (p *indexPermutation) encrypt(x uint64) uint64 {
+	left, right := x>>p.halfBits, x&p.mask
+	for _, key := range p.keys {
+		left, right = right, left^(splitMix64(right^key)&p.mask)
+	}
+	return left<<p.halfBits | right
+}
+
+// splitMix64 SplitMix64 的混合函数，输入的每一位都会影响输出的所有位
+func splitMix64(x uint64) uint64 {
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// sample 从 r 范围内按种子均匀地随机抽取最多 n 个不重复的有效组合
+// 相同的种子得到相同的顺序；无效组合以空字符串占位，使序号与抽样位置一一对应，
+// 便于检查点记录进度
+func (p *Pattern) sample(ctx context.Context, r IndexRange, n uint64, seed int64) <-chan string {
+	perm := newIndexPermutation(r.Len(), seed)
+	domainChan := make(chan string, 1024)
+	go func() {
+		defer close(domainChan)
+		var sampled uint64
+		for i := uint64(0); i < r.Len() && sampled < n; i++ {
+			domain, ok := p.candidate(r.Start + perm.at(i))
+			if ok {
+				sampled++
+			} else {
+				domain = ""
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case domainChan <- domain:
+			}
+		}
+	}()
+	return domainChan
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestIndexPermutationBijection(t *testing.T) {
+	// 包括 2 的偶数次幂、奇数次幂和需要 cycle walking 的非 2 的幂
+	for _, n := range []uint64{1, 2, 3, 4, 5, 7, 8, 15, 16, 17, 100, 255, 256, 1000, 4097} {
+		for _, seed := range []int64{0, 1, -7, 42} {
+			perm := newIndexPermutation(n, seed)
+			seen := make([]bool, n)
+			for i := uint64(0); i < n; i++ {
+				x := perm.at(i)
+				if x >= n {
+					t.Fatalf("n=%d seed=%d: at(%d) = %d out of range", n, seed, i, x)
+				}
+				if seen[x] {
+					t.Fatalf("n=%d seed=%d: at(%d) = %d repeated", n, seed, i, x)
+				}
+				seen[x] = true
+			}
+		}
+	}
+}
+
+func TestIndexPermutationSeed(t *testing.T) {
+	order := func(n uint64, seed int64) []uint64 {
+		perm := newIndexPermutation(n, seed)
+		values := make([]uint64, n)
+		for i := range values {
+			values[i] = perm.at(uint64(i))
+		}
+		return values
+	}
+
+	const n = 1000
+	if !slices.Equal(order(n, 42), order(n, 42)) {
+		t.Error("same seed produced different orders")
+	}
+	if slices.Equal(order(n, 42), order(n, 43)) {
+		t.Error("different seeds produced the same order")
+	}
+	identity := make([]uint64, n)
+	for i := range identity {
+		identity[i] = uint64(i)
+	}
+	if slices.Equal(order(n, 42), identity) {
+		t.Error("permutation is the identity")
+	}
+}
+
+func TestPatternSample(t *testing.T) {
+	p, err := ParsePattern("[a-c-]{2}.com", nil)
+	if err != nil {
+		t.Fatalf("ParsePattern: %v", err)
+	}
+	r := IndexRange{Start: 0, End: p.Size()}
+
+	collect := func(n uint64, seed int64) []string {
+		var domains []string
+		for domain := range p.sample(context.Background(), r, n, seed) {
+			if domain != "" {
+				domains = append(domains, domain)
+			}
+		}
+		return domains
+	}
+
+	// 抽取全部时得到所有有效组合，只是顺序不同
+	all := collect(p.Size(), 7)
+	var want []string
+	for domain := range p.Stream(context.Background(), r.Start, r.End) {
+		want = append(want, domain)
+	}
+	sorted := slices.Sorted(slices.Values(all))
+	slices.Sort(want)
+	if !slices.Equal(sorted, want) {
+		t.Errorf("full sample = %v, want permutation of %v", all, want)
+	}
+
+	if got := collect(3, 7); !slices.Equal(got, all[:3]) {
+		t.Errorf("sample of 3 = %v, want prefix %v", got, all[:3])
+	}
+	if !slices.Equal(collect(5, 11), collect(5, 11)) {
+		t.Error("same seed produced different samples")
+	}
+}
@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// 评分中发音与键盘两部分的权重
+const (
+	pronounceWeight = 0.75
+	keyboardWeight  = 0.25
+)
+
+// keyboardRows QWERTY 键盘各行的按键及相对第一行的水平偏移
+var keyboardRows = []struct {
+	keys   string
+	offset float64
+}{
+	{"1234567890", 0},
+	{"qwertyuiop", 0.5},
+	{"asdfghjkl", 0.75},
+	{"zxcvbnm", 1.25},
+}
+
+// maxKeyDistance 键盘上两键之间的最大距离，用于把平均距离换算为 0 到 1 的分数
+const maxKeyDistance = 9.5
+
+// bigramModel 英文字母二元组模型，由内置词表统计得到
+// 状态 0 表示词的开头或结尾，1 到 26 表示 a 到 z
+type bigramModel struct {
+	logProb [27][27]float64
+	// randomLog、wordLog 随机字母串与真实单词的平均对数概率，用于把对数概率换算为 0 到 100
+	randomLog float64
+	wordLog   float64
+}
+
+var (
+	bigramOnce   sync.Once
+	bigramScorer *bigramModel
+)
+
+// englishBigrams 返回由内置词表训练的二元组模型
+func englishBigrams() *bigramModel {
+	bigramOnce.Do(func() {
+		var words []string
+		for _, name := range WordlistNames() {
+			list, err := loadWordlist(name, &PatternConfig{})
+			if err == nil {
+				words = append(words, list...)
+			}
+		}
+		bigramScorer = newBigramModel(words)
+	})
+	return bigramScorer
+}
+
+func newBigramModel(words []string) *bigramModel {
+	var counts [27][27]float64
+	for _, word := range words {
+		prev := 0
+		for _, c := range word {
+			if c < 'a' || c > 'z' {
+				prev = 0
+				continue
+			}
+			counts[prev][c-'a'+1]++
+			prev = int(c - 'a' + 1)
+		}
+		counts[prev][0]++
+	}
+
+	m := &bigramModel{}
+	for i := range counts {
+		var total float64
+		for _, n := range counts[i] {
+			total += n
+		}
+		// 加一平滑，没有出现过的组合也有很小的概率
+		for j, n := range counts[i] {
+			m.logProb[i][j] = math.Log((n + 1) / (total + 27))
+		}
+	}
+
+	// 随机字母串中每个转移的期望对数概率
+	var sum float64
+	for i := 1; i < 27; i++ {
+		for j := 1; j < 27; j++ {
+			sum += m.logProb[i][j]
+		}
+	}
+	m.randomLog = sum / (26 * 26)
+
+	var wordSum float64
+	for _, word := range words {
+		wordSum += m.averageLog(word)
+	}
+	if len(words) > 0 {
+		m.wordLog = wordSum / float64(len(words))
+	}
+	return m
+}
+
+// averageLog 计算名称中每个转移的平均对数概率，数字和连字符按随机字符计算
+func (m *bigramModel) averageLog(name string) float64 {
+	var sum float64
+	var transitions int
+	prev := 0
+	for _, c := range name {
+		transitions++
+		if c < 'a' || c > 'z' {
+			sum += m.randomLog
+			prev = 0
+			continue
+		}
+		sum += m.logProb[prev][c-'a'+1]
+		prev = int(c - 'a' + 1)
+	}
+	sum += m.logProb[prev][0]
+	transitions++
+	return sum / float64(transitions)
+}
+
+// pronounceability 名称的可读性，0 到 100，真实英文单词的平均水平约为 100
+func (m *bigramModel) pronounceability(name string) float64 {
+	if m.wordLog <= m.randomLog {
+		return 0
+	}
+	score := (m.averageLog(name) - m.randomLog) / (m.wordLog - m.randomLog)
+	return math.Max(0, math.Min(1, score)) * 100
+}
+
+// keyPosition 返回按键在键盘上的坐标
+func keyPosition(c rune) (float64, float64, bool) {
+	for row, r := range keyboardRows {
+		if col := strings.IndexRune(r.keys, c); col >= 0 {
+			return float64(col) + r.offset, float64(row), true
+		}
+	}
+	return 0, 0, false
+}
+
+// KeyboardDistance 输入名称时相邻两键的平均距离（以键宽为单位），越小越容易输入
+func KeyboardDistance(name string) float64 {
+	var total float64
+	var moves int
+	var prevX, prevY float64
+	hasPrev := false
+	for _, c := range name {
+		x, y, ok := keyPosition(c)
+		if !ok {
+			hasPrev = false
+			continue
+		}
+		if hasPrev {
+			total += math.Hypot(x-prevX, y-prevY)
+			moves++
+		}
+		prevX, prevY, hasPrev = x, y, true
+	}
+	if moves == 0 {
+		return 0
+	}
+	return total / float64(moves)
+}
+
+// NameScore 名称质量评分
+type NameScore struct {
+	Score            float64 `json:"score"`            // 综合评分，0 到 100
+	Pronounceability float64 `json:"pronounceability"` // 按英文字母二元组频率计算的可读性，0 到 100
+	KeyboardDistance float64 `json:"keyboard_distance"`
+}
+
+// ScoreName 计算域名中第一个标签的质量评分
+func ScoreName(domain string) NameScore {
+	name := domainName(domain)
+	pronounce := englishBigrams().pronounceability(name)
+	distance := KeyboardDistance(name)
+	keyboardEase := math.Max(0, 1-distance/maxKeyDistance) * 100
+
+	return NameScore{
+		Score:            roundScore(pronounceWeight*pronounce + keyboardWeight*keyboardEase),
+		Pronounceability: roundScore(pronounce),
+		KeyboardDistance: roundScore(distance),
+	}
+}
+
+func roundScore(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// domainName 返回域名的第一个标签（小写），即生成模式中通常变化的部分
+func domainName(domain string) string {
+	name, _, _ := strings.Cut(strings.ToLower(domain), ".")
+	return name
+}
+
+// NameShape 名称的辅音/元音结构: C 辅音、V 元音（aeiou）、D 数字，其他字符保持不变
+func NameShape(name string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(name) {
+		switch {
+		case strings.ContainsRune("aeiou", c):
+			b.WriteByte('V')
+		case c >= 'a' && c <= 'z':
+			b.WriteByte('C')
+		case c >= '0' && c <= '9':
+			b.WriteByte('D')
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// NameFilter 生成域名的质量过滤条件，作用于域名的第一个标签
+type NameFilter struct {
+	Shapes               []string // 允许的辅音/元音结构，例如 CVCV，为空时不限制
+	Banned               []string // 不能包含的子串
+	NoDigitsAfterLetters bool     // 字母之后不能再出现数字，例如拒绝 ab1，允许 1ab
+	MinScore             float64  // 最低综合评分
+}
+
+// normalize 统一结构和子串的大小写，检查结构是否有效
+func (f *NameFilter) normalize() error {
+	for i, shape := range f.Shapes {
+		f.Shapes[i] = strings.ToUpper(strings.TrimSpace(shape))
+		if strings.Trim(f.Shapes[i], "CVD-") != "" {
+			return fmt.Errorf("无效的名称结构: %s (只能包含 C、V、D 和 -)", shape)
+		}
+	}
+	for i, banned := range f.Banned {
+		f.Banned[i] = strings.ToLower(strings.TrimSpace(banned))
+	}
+	f.Banned = slices.DeleteFunc(f.Banned, func(s string) bool { return s == "" })
+	return nil
+}
+
+// allow 判断域名是否满足所有过滤条件
+func (f *NameFilter) allow(domain string) bool {
+	name := domainName(domain)
+
+	if len(f.Shapes) > 0 && !slices.Contains(f.Shapes, NameShape(name)) {
+		return false
+	}
+	for _, banned := range f.Banned {
+		if strings.Contains(name, banned) {
+			return false
+		}
+	}
+	if f.NoDigitsAfterLetters && hasDigitAfterLetter(name) {
+		return false
+	}
+	if f.MinScore > 0 && ScoreName(domain).Score < f.MinScore {
+		return false
+	}
+	return true
+}
+
+func hasDigitAfterLetter(name string) bool {
+	seenLetter := false
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z':
+			seenLetter = true
+		case c >= '0' && c <= '9' && seenLetter:
+			return true
+		}
+	}
+	return false
+}
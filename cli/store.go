@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sqliteDriverName SQLite 驱动注册的名称（modernc.org/sqlite，纯 Go 实现，不需要 CGO）
+const sqliteDriverName = "sqlite"
+
+// storeBatchSize 每个事务写入的结果数，逐条提交在大批量扫描时太慢
+const storeBatchSize = 500
+
+// storeSchema 结果表结构，每个域名一行，重复扫描时更新
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	domain             TEXT PRIMARY KEY,
+	input_domain       TEXT NOT NULL,
+	unicode_domain     TEXT NOT NULL DEFAULT '',
+	success            INTEGER NOT NULL,
+	status             TEXT NOT NULL,
+	protocol           TEXT NOT NULL DEFAULT '',
+	registrar          TEXT NOT NULL DEFAULT '',
+	creation_date      TEXT NOT NULL DEFAULT '',
+	expiration_date    TEXT NOT NULL DEFAULT '',
+	name_servers       TEXT NOT NULL DEFAULT '',
+	whois_server       TEXT NOT NULL DEFAULT '',
+	registrar_server   TEXT NOT NULL DEFAULT '',
+	registry_response  TEXT NOT NULL DEFAULT '',
+	registrar_response TEXT NOT NULL DEFAULT '',
+	error              TEXT NOT NULL DEFAULT '',
+	error_category     TEXT NOT NULL DEFAULT '',
+	attempts           INTEGER NOT NULL DEFAULT 0,
+	duration_ms        INTEGER NOT NULL DEFAULT 0,
+	first_seen         TEXT NOT NULL,
+	queried_at         TEXT NOT NULL,
+	scans              INTEGER NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS idx_results_status ON results(status);
+CREATE INDEX IF NOT EXISTS idx_results_expiration_date ON results(expiration_date);
+`
+
+// storeColumns 按插入顺序排列的列
+var storeColumns = []string{
+	"domain", "input_domain", "unicode_domain", "success", "status", "protocol",
+	"registrar", "creation_date", "expiration_date", "name_servers",
+	"whois_server", "registrar_server", "registry_response", "registrar_response",
+	"error", "error_category", "attempts", "duration_ms", "first_seen", "queried_at",
+}
+
+// storeParsedColumns 来自成功查询的列，查询失败时保留上一次成功扫描的值
+var storeParsedColumns = []string{
+	"unicode_domain", "status", "protocol", "registrar", "creation_date",
+	"expiration_date", "name_servers", "whois_server", "registrar_server",
+	"registry_response", "registrar_response",
+}
+
+// resultStore 将查询结果保存到 SQLite 数据库
+// 同一域名在后续扫描中更新为最新结果，first_seen 和 scans 记录扫描历史
+type resultStore struct {
+	mu      sync.Mutex
+	db      *sql.DB
+	upsert  string
+	pending []*ResultRecord
+}
+
+// openResultStore 按 "sqlite:path.db" 格式打开结果库
+func openResultStore(spec string) (*resultStore, error) {
+	kind, path, ok := strings.Cut(spec, ":")
+	if !ok || kind != "sqlite" || path == "" {
+		return nil, fmt.Errorf("无效的结果库配置: %s (需要格式: sqlite:path.db)", spec)
+	}
+
+	db, err := sql.Open(sqliteDriverName, path)
+	if err != nil {
+		return nil, fmt.Errorf("打开结果库失败: %w", err)
+	}
+	// SQLite 只允许一个写连接
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "PRAGMA synchronous=NORMAL", storeSchema} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("初始化结果库失败: %w", err)
+		}
+	}
+
+	return &resultStore{db: db, upsert: upsertStatement()}, nil
+}
+
+// upsertStatement 构造插入或更新语句
+func upsertStatement() string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(storeColumns)), ", ")
+
+	updates := []string{
+		"input_domain = excluded.input_domain",
+		"success = excluded.success",
+		"error = excluded.error",
+		"error_category = excluded.error_category",
+		"attempts = excluded.attempts",
+		"duration_ms = excluded.duration_ms",
+		"queried_at = excluded.queried_at",
+		"scans = results.scans + 1",
+	}
+	for _, column := range storeParsedColumns {
+		updates = append(updates, fmt.Sprintf("%[1]s = CASE WHEN excluded.success = 1 THEN excluded.%[1]s ELSE results.%[1]s END", column))
+	}
+
+	return fmt.Sprintf("INSERT INTO results (%s) VALUES (%s) ON CONFLICT(domain) DO UPDATE SET %s",
+		strings.Join(storeColumns, ", "), placeholders, strings.Join(updates, ", "))
+}
+
+// save 缓存一条结果，累积到 storeBatchSize 条后在一个事务中写入
+func (s *resultStore) save(record *ResultRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, record)
+	if len(s.pending) < storeBatchSize {
+		return nil
+	}
+	return s.flushLocked()
+}
+
+// flush 写入所有缓存的结果
+func (s *resultStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+func (s *resultStore) flushLocked() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(s.upsert)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range s.pending {
+		if _, err := stmt.Exec(storeValues(record)...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("写入 %s 失败: %w", record.Domain, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// close 写入剩余结果并关闭数据库
+func (s *resultStore) close() error {
+	err := s.flush()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// storeValues 按 storeColumns 的顺序返回记录的值
+func storeValues(record *ResultRecord) []any {
+	// 无效域名没有 A-label，以原始输入作为主键
+	key := record.ASCIIDomain
+	if key == "" {
+		key = record.Domain
+	}
+	success := 0
+	if record.Success {
+		success = 1
+	}
+	info := record.info()
+	queriedAt := record.QueriedAt.UTC().Format(time.RFC3339)
+
+	return []any{
+		key, record.Domain, record.UnicodeDomain, success, record.Status, record.Protocol,
+		info.Registrar, info.CreationDate, info.ExpirationDate, strings.Join(info.NameServers, " "),
+		record.WhoisServer, record.RegistrarServer, record.RegistryResponse, record.RegistrarResponse,
+		record.Error, record.ErrorCategory, record.Attempts, record.DurationMS, queriedAt, queriedAt,
+	}
+}
@@ -0,0 +1,4 @@
+package cli
+
+// 注册纯 Go 实现的 SQLite 驱动，供 --store 使用，不需要 CGO
+import _ "modernc.org/sqlite"
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"gois/whois"
+)
+
+// TLDFilePath 返回 TLD 覆盖文件路径，未指定时使用默认的用户配置文件
+func TLDFilePath(config *QueryConfig) (string, error) {
+	if config.TLDFile != "" {
+		return config.TLDFile, nil
+	}
+	path, err := whois.DefaultTLDOverridePath()
+	if err != nil {
+		return "", fmt.Errorf("获取 TLD 覆盖文件路径失败: %w", err)
+	}
+	return path, nil
+}
+
+// TLDRefreshPath 返回 tld refresh 生成的映射文件路径
+func TLDRefreshPath() (string, error) {
+	path, err := whois.DefaultTLDRefreshPath()
+	if err != nil {
+		return "", fmt.Errorf("获取 TLD 刷新文件路径失败: %w", err)
+	}
+	return path, nil
+}
+
+// ConfigureBaseTLDRegistry 存在 tld refresh 生成的映射时用它替换内嵌映射
+func ConfigureBaseTLDRegistry(registry *whois.TLDRegistry) error {
+	path, err := TLDRefreshPath()
+	if err != nil {
+		return err
+	}
+	if err := registry.LoadBase(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("加载 TLD 刷新文件失败: %w", err)
+	}
+	return nil
+}
+
+// ConfigureTLDRegistry 按优先级从低到高依次叠加 tld refresh 生成的映射、TLD 缓存文件和用户覆盖文件
+// 显式指定的覆盖文件必须存在，默认路径的覆盖文件不存在时忽略
+func ConfigureTLDRegistry(registry *whois.TLDRegistry, config *QueryConfig) error {
+	if err := ConfigureBaseTLDRegistry(registry); err != nil {
+		return err
+	}
+
+	if config.TLDCache {
+		cachePath, err := whois.DefaultTLDCachePath()
+		if err != nil {
+			return fmt.Errorf("获取 TLD 缓存路径失败: %w", err)
+		}
+		if err := registry.EnableCache(cachePath); err != nil {
+			return fmt.Errorf("加载 TLD 缓存失败: %w", err)
+		}
+	}
+
+	path, err := TLDFilePath(config)
+	if err != nil {
+		return err
+	}
+	if err := registry.LoadOverrides(path); err != nil {
+		if config.TLDFile == "" && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("加载 TLD 覆盖文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshTLDServers 从 IANA 重新生成 TLD 到 WHOIS 服务器的映射
+// 顶级域名列表下载失败时使用 base 中的顶级域名；查询失败的 TLD 和 base 中的二级后缀（如 gov.uk）沿用 base 中的服务器
+func RefreshTLDServers(ctx context.Context, config *QueryConfig, base map[string]string, logger *slog.Logger) (map[string]string, error) {
+	client, err := whois.NewClient(config.Timeout, config.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 WHOIS 客户端失败: %w", err)
+	}
+
+	tlds, err := whois.DownloadIANATLDList(ctx, whois.NewHTTPClient(config.Timeout, config.Proxy))
+	if err != nil {
+		logger.Warn("下载 IANA 顶级域名列表失败，使用现有列表", "error", err)
+		tlds = nil
+		for tld := range base {
+			if !isSecondLevelSuffix(tld) {
+				tlds = append(tlds, tld)
+			}
+		}
+	}
+
+	refreshed := make(map[string]string, len(tlds))
+	for tld, server := range base {
+		if isSecondLevelSuffix(tld) {
+			refreshed[tld] = server
+		}
+	}
+
+	workerCount := config.Concurrency
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+
+	tldChan := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var processed, succeeded int
+
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tld := range tldChan {
+				server, err := client.LookupIANAWhoisServer(ctx, tld)
+
+				mu.Lock()
+				processed++
+				var noServer *whois.NoWhoisServerFoundError
+				switch {
+				case err == nil:
+					succeeded++
+					refreshed[tld] = server
+				case errors.As(err, &noServer):
+					// IANA 明确没有 WHOIS 服务器，从映射中移除
+					succeeded++
+				case ctx.Err() == nil:
+					// 查询失败时沿用现有服务器，避免丢失数据
+					if old, ok := base[tld]; ok {
+						refreshed[tld] = old
+					}
+					logger.Warn("查询 IANA 失败", "tld", tld, "error", err)
+				}
+				if processed%100 == 0 {
+					logger.Info("刷新进度", "completed", processed, "total", len(tlds))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, tld := range tlds {
+		select {
+		case <-ctx.Done():
+			break feed
+		case tldChan <- tld:
+		}
+	}
+	close(tldChan)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if succeeded == 0 && len(tlds) > 0 {
+		return nil, fmt.Errorf("所有 IANA 查询均失败")
+	}
+
+	return refreshed, nil
+}
+
+// isSecondLevelSuffix 是否为多标签后缀，例如 gov.uk
+func isSecondLevelSuffix(tld string) bool {
+	return strings.Contains(tld, ".")
+}
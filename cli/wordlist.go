@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed wordlists/*.txt
+var embeddedWordlists embed.FS
+
+// PatternConfig 模式解析配置
+type PatternConfig struct {
+	// Wordlists 词表名称到文件路径的映射，优先于内置词表
+	Wordlists map[string]string
+	// Filter 名称质量过滤条件，为 nil 时只检查 LDH 规则
+	Filter *NameFilter
+}
+
+// wordlistFilter 词表占位符的过滤条件，例如 {@nouns:min=3,max=6,limit=100}
+type wordlistFilter struct {
+	minLength int // 最小长度（字符数），0 表示不限制
+	maxLength int // 最大长度（字符数），0 表示不限制
+	limit     int // 最多使用前多少个词，0 表示不限制
+}
+
+// WordlistNames 返回内置词表的名称
+func WordlistNames() []string {
+	entries, _ := fs.ReadDir(embeddedWordlists, "wordlists")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".txt"))
+	}
+	return names
+}
+
+// ParseWordlistFlags 解析 name=path 格式的词表参数
+func ParseWordlistFlags(values []string) (map[string]string, error) {
+	wordlists := make(map[string]string, len(values))
+	for _, value := range values {
+		name, filePath, ok := strings.Cut(value, "=")
+		name, filePath = strings.TrimSpace(name), strings.TrimSpace(filePath)
+		if !ok || name == "" || filePath == "" {
+			return nil, fmt.Errorf("无效的词表参数: %s (需要格式: name=path)", value)
+		}
+		wordlists[name] = filePath
+	}
+	return wordlists, nil
+}
+
+// parseWordlistPlaceholder 解析占位符 @ 之后的内容，返回词表名称和过滤条件
+// 过滤条件写在最后一个冒号之后，例如 nouns:max=6；冒号后没有 = 时整体视为名称
+func parseWordlistPlaceholder(content string) (string, wordlistFilter, error) {
+	var filter wordlistFilter
+
+	name := content
+	if i := strings.LastIndex(content, ":"); i >= 0 && strings.Contains(content[i+1:], "=") {
+		name = content[:i]
+		for _, option := range strings.Split(content[i+1:], ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return "", filter, fmt.Errorf("词表 %s 的过滤条件无效: %s", name, option)
+			}
+			switch strings.TrimSpace(key) {
+			case "min":
+				filter.minLength = n
+			case "max":
+				filter.maxLength = n
+			case "limit":
+				filter.limit = n
+			default:
+				return "", filter, fmt.Errorf("词表 %s 的过滤条件无效: %s (可选: min, max, limit)", name, option)
+			}
+		}
+	}
+
+	if name = strings.TrimSpace(name); name == "" {
+		return "", filter, fmt.Errorf("词表占位符缺少名称")
+	}
+	return name, filter, nil
+}
+
+// loadWordlist 按名称加载词表：先查找 --wordlist 指定的文件，再查找内置词表，最后把名称当作文件路径
+// 词统一转为小写，去掉空行、注释和重复的词，保持文件中的顺序
+func loadWordlist(name string, config *PatternConfig) ([]string, error) {
+	var content []byte
+	var err error
+
+	if filePath, ok := config.Wordlists[name]; ok {
+		content, err = readFile(filePath)
+	} else if content, err = embeddedWordlists.ReadFile(path.Join("wordlists", name+".txt")); err != nil {
+		content, err = readFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("未知的词表: %s (内置词表: %s，也可以使用文件路径或 --wordlist name=path)",
+				name, strings.Join(WordlistNames(), ", "))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取词表 %s 失败: %w", name, err)
+	}
+
+	var words []string
+	seen := make(map[string]struct{})
+	for _, line := range strings.Split(string(content), "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		words = append(words, word)
+	}
+	return words, nil
+}
+
+// apply 按过滤条件筛选词表
+func (f wordlistFilter) apply(words []string) []string {
+	filtered := slices.DeleteFunc(slices.Clone(words), func(word string) bool {
+		length := utf8.RuneCountInString(word)
+		return (f.minLength > 0 && length < f.minLength) || (f.maxLength > 0 && length > f.maxLength)
+	})
+	if f.limit > 0 && len(filtered) > f.limit {
+		filtered = filtered[:f.limit]
+	}
+	return filtered
+}
@@ -8,38 +8,84 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	inputColumn   int
+	inputJSONPath string
+	dedupeInput   bool
+)
+
 var batchCmd = &cobra.Command{
 	Use:   "batch [file]",
 	Short: "批量查询域名",
-	Long: `从文件中读取域名列表并批量查询
+	Long: `从文件或标准输入中读取域名列表并批量查询
 
 文件格式:
   - 每行一个域名
   - 支持 # 开头的注释行
   - 空行会被忽略
 
+文件为 - 时从标准输入读取。域名列表逐行读取，不会整个载入内存，
+可以处理很大的文件或管道输入；使用 --dedupe 时内存随不重复的域名数增长。
+
+每行会先整理为可注册域名:
+  - 从 URL、邮箱地址中提取主机名，去掉端口和末尾的点
+  - 子域名归约为可注册域名，例如 www.example.co.uk -> example.co.uk
+  - 普通文本行只取第一个字段，去掉 # 之后的行内注释
+  - 默认跳过与最近 65536 个不重复域名之一相同的域名，相隔更远的重复域名会再次查询；
+    --dedupe 跳过整个列表中重复的域名
+无法提取域名的行会输出行号和原因后跳过。
+
 示例:
   gois batch domains.txt
   gois batch domains.txt -c 10
-  gois batch domains.txt -m simple -o results.csv`,
+  gois batch domains.txt -m simple -o results.csv
+  gois batch domains.txt -o results.csv --checkpoint batch.ckpt
+  gois batch domains.txt -o results.csv --checkpoint batch.ckpt --resume
+  cat zone.txt | gois batch - -m simple -o results.csv
+  gois batch export.csv --column 2 -m simple
+  gois batch certs.ndjson --json-path subject.common_name -m simple`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		filePath := args[0]
 
-		// 检查文件是否存在
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			logger.Error("文件不存在", "file", filePath)
+		if inputColumn < 0 {
+			logger.Error("无效的列号", "column", inputColumn)
 			os.Exit(1)
 		}
+		if inputColumn > 0 && inputJSONPath != "" {
+			logger.Error("--column 和 --json-path 不能同时使用")
+			os.Exit(1)
+		}
+		inputConfig := cli.InputConfig{
+			Column:   inputColumn,
+			JSONPath: inputJSONPath,
+			Dedupe:   dedupeInput,
+		}
+
+		// 普通文件先统计域名数，用于显示进度和校验检查点；标准输入只能读取一次，总数未知
+		totalCount := int64(-1)
+		if filePath != cli.StdinPath {
+			count, err := countDomains(filePath, inputConfig)
+			if err != nil {
+				logger.Error("加载域名列表失败", "error", err)
+				os.Exit(1)
+			}
+			if count == 0 {
+				logger.Error("加载域名列表失败", "error", "文件中没有找到有效的域名")
+				os.Exit(1)
+			}
+			totalCount = count
+			logger.Info("从文件加载域名列表", "file", filePath, "count", count)
+		} else {
+			logger.Info("从标准输入读取域名列表")
+		}
 
-		// 加载域名列表
-		domains, err := cli.LoadDomainsFromFile(filePath)
+		input, err := cli.OpenDomainList(filePath)
 		if err != nil {
 			logger.Error("加载域名列表失败", "error", err)
 			os.Exit(1)
 		}
-
-		logger.Info("从文件加载域名列表", "file", filePath, "count", len(domains))
+		defer input.Close()
 
 		// 创建 CLI 实例
 		cliInstance, err := createCLI()
@@ -50,14 +96,43 @@ var batchCmd = &cobra.Command{
 		defer cliInstance.Close()
 
 		// 批量查询
-		summary := cliInstance.QueryBatchDomains(domains)
-
-		if summary.HasFailures() {
-			os.Exit(1)
+		// 统计时不输出被跳过的行，读取时再逐行输出
+		inputConfig.OnReject = func(r cli.Rejection) {
+			logger.Warn("跳过无法识别的行", "line", r.Line, "text", r.Text, "reason", r.Reason)
 		}
+		reader := cli.NewDomainReader(input, &inputConfig)
+		summary := cliInstance.QueryBatchDomainsStream(cmd.Context(), reader.Stream(cmd.Context()), totalCount)
+		if summary.Err == nil && !summary.Interrupted {
+			if err := reader.Err(); err != nil {
+				logger.Error("读取域名列表中断", "error", err)
+				summary.Err = err
+			}
+			stats := reader.Stats()
+			logger.Info("域名列表整理完成",
+				"lines", stats.Lines,
+				"domains", stats.Accepted,
+				"duplicates", stats.Duplicates,
+				"rejected", stats.Rejected)
+		}
+
+		exitWithSummary(cliInstance, summary)
 	},
 }
 
+// countDomains 逐行统计文件中整理后的域名数
+func countDomains(filePath string, config cli.InputConfig) (int64, error) {
+	file, err := cli.OpenDomainList(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return cli.NewDomainReader(file, &config).Count()
+}
+
 func init() {
+	batchCmd.Flags().IntVar(&inputColumn, "column", 0, "按 CSV 解析每一行，从第 n 列（从 1 开始）读取域名")
+	batchCmd.Flags().StringVar(&inputJSONPath, "json-path", "", "每行是一个 JSON 对象时域名所在的字段，用点分隔，例如 host.name、records.0.domain")
+	batchCmd.Flags().BoolVar(&dedupeInput, "dedupe", false, "跳过整个列表中重复的域名，需要在内存中记录所有已读取的域名（默认只跳过与最近 65536 个不重复域名之一相同的域名）")
+	addCheckpointFlags(batchCmd)
 	rootCmd.AddCommand(batchCmd)
 }
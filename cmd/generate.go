@@ -1,14 +1,32 @@
 package cmd
 
 import (
-	"math"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"gois/cli"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	generateShard string
+	generateRange string
+	wordlists     []string
+	sampleSize    uint64
+	sampleSeed    int64
+	// 名称质量过滤与评分
+	nameShapes           []string
+	bannedSubstrings     []string
+	noDigitsAfterLetters bool
+	minScore             float64
+	scoreNames           bool
+	sortByScore          bool
+)
+
 var generateCmd = &cobra.Command{
 	Use:   "generate [pattern]",
 	Short: "从模式生成域名并查询",
@@ -20,39 +38,118 @@ var generateCmd = &cobra.Command{
   - [0-9]: 数字 0-9
   - [abc]: 自定义字符集
   - {n}: 重复 n 次
+  - {min,max}: 重复 min 到 max 次
+  - ?: 前一个字符或分段可有可无
+  - (a|b): 多选一，选项中可以继续使用以上语法
+  - {com,net}: 从集合中选一个，常用于后缀
+  - {@nouns}: 词表中的一个词，可以是内置词表、--wordlist 指定的名称或文件路径
+  - {@nouns:min=3,max=6,limit=100}: 按长度过滤词表，limit 只使用前若干个词
+  - \[ \] \( \) \{ \} \? \|: 转义为普通字符
 
 示例:
   gois generate "[a-z]{3}.com"              # 生成所有 3 字符小写字母域名
   gois generate "test[0-9]{2}.net"          # test + 两位数字
   gois generate "[abc]{2}.org"              # abc 的 2 字符组合
   gois generate "[a-z]{2}[0-9].com" -c 10   # 并发 10
-  gois generate "[0-9]{4}.io" -m simple -o results.csv`,
+  gois generate "[a-z]{2,4}.io"             # 2 到 4 字符
+  gois generate "(shop|store|mart)[0-9]{2}.com"
+  gois generate "brand.{com,net,org,io}"    # 多个后缀
+  gois generate "get-?app.com"              # getapp.com 和 get-app.com
+  gois generate "{@adjectives}{@nouns:max=5}.com"
+  gois generate "{@brands}{@nouns}.io" --wordlist brands=brands.txt
+  gois generate "[0-9]{4}.io" -m simple -o results.csv
+  gois generate "[a-z]{4}.com" -m simple -o results.csv --checkpoint gen.ckpt --resume
+  gois generate "[a-z0-9]{5}.com" -m simple --shard 2/4     # 分 4 份查询第 2 份
+  gois generate "[a-z]{4}.com" -m simple --range 1000:2000  # 序号 1000 到 1999
+  gois generate "[a-z0-9]{6}.com" -m simple --sample 1000 --seed 42   # 随机抽样 1000 个
+  gois generate "[a-z]{4}.com" -m simple --shape CVCV --sort-score -o ranked.csv
+  gois generate "[a-z]{5}.io" -m simple --min-score 60 --ban q,x,zz`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		pattern := args[0]
 
-		// 生成域名流
+		// 解析模式
 		logger.Info("正在从模式生成域名", "pattern", pattern)
-		domainStream, totalCount, err := cli.GenerateDomainsFromPattern(pattern)
+		wordlistFiles, err := cli.ParseWordlistFlags(wordlists)
+		if err != nil {
+			logger.Error("解析词表参数失败", "error", err)
+			os.Exit(1)
+		}
+		patternConfig := &cli.PatternConfig{Wordlists: wordlistFiles}
+		if len(nameShapes) > 0 || len(bannedSubstrings) > 0 || noDigitsAfterLetters || minScore > 0 {
+			patternConfig.Filter = &cli.NameFilter{
+				Shapes:               nameShapes,
+				Banned:               bannedSubstrings,
+				NoDigitsAfterLetters: noDigitsAfterLetters,
+				MinScore:             minScore,
+			}
+		}
+		p, err := cli.ParsePattern(pattern, patternConfig)
 		if err != nil {
 			logger.Error("生成域名失败", "error", err)
 			os.Exit(1)
 		}
 
-		logger.Info("域名生成完成", "count", totalCount)
+		// 计算本次查询的序号范围
+		indexRange := cli.IndexRange{Start: 0, End: p.Size()}
+		if generateRange != "" {
+			if indexRange, err = cli.ParseIndexRange(generateRange, p.Size()); err != nil {
+				logger.Error("解析范围失败", "error", err)
+				os.Exit(1)
+			}
+		}
+		if generateShard != "" {
+			i, n, err := cli.ParseShard(generateShard)
+			if err != nil {
+				logger.Error("解析分片失败", "error", err)
+				os.Exit(1)
+			}
+			indexRange = indexRange.Shard(i, n)
+		}
+		// 统计有效组合数，不符合 LDH 规则或过滤条件的组合不计入
+		totalCount, err := p.Count(cmd.Context(), indexRange)
+		if err != nil {
+			logger.Error("统计域名数量失败", "error", err)
+			os.Exit(1)
+		}
+
+		logger.Info("域名生成完成",
+			"count", totalCount,
+			"filtered", indexRange.Len()-totalCount,
+			"total", p.Size(),
+			"range", fmt.Sprintf("%d:%d", indexRange.Start, indexRange.End))
+
+		// 随机抽样时只查询其中的 sampleSize 个
+		queryCount := totalCount
+		if sampleSize > 0 {
+			if !cmd.Flags().Changed("seed") {
+				if resume {
+					logger.Error("从检查点恢复随机抽样时需要指定与上次相同的 --seed")
+					os.Exit(1)
+				}
+				sampleSeed = time.Now().UnixNano()
+			}
+			queryCount = min(sampleSize, totalCount)
+			logger.Info("随机抽样", "sample", queryCount, "seed", sampleSeed)
+		}
 
 		// 大数量警告
 		switch {
-		case totalCount > 10_000:
+		case queryCount > 10_000:
 			logger.Warn("将查询大量域名，可能需要很长时间",
-				"count", totalCount,
-				"suggestion", "使用更小的字符集或减少重复次数")
-		case totalCount > 1_000:
+				"count", queryCount,
+				"suggestion", "使用更小的字符集或减少重复次数，或使用 --shard 分到多台机器，或先用 --sample 抽样估计")
+		case queryCount > 1_000:
 			logger.Info("将查询较多域名，建议使用较高的并发数",
-				"count", totalCount,
+				"count", queryCount,
 				"suggestion", "使用 -c 参数增加并发数")
 		}
 
+		// 评分时 CSV 输出默认带上评分列
+		if (scoreNames || sortByScore || minScore > 0) && len(columns) == 0 {
+			columns = append(slices.Clone(cli.DefaultCSVColumns), "score")
+		}
+
 		// 创建 CLI 实例
 		cliInstance, err := createCLI()
 		if err != nil {
@@ -62,18 +159,47 @@ var generateCmd = &cobra.Command{
 		defer cliInstance.Close()
 
 		// 批量查询
-		totalHint := int64(-1)
-		if totalCount <= uint64(math.MaxInt64) {
-			totalHint = int64(totalCount)
+		var summary *cli.BatchSummary
+		if sampleSize > 0 {
+			summary = cliInstance.QueryPatternSample(cmd.Context(), p, indexRange, sampleSize, sampleSeed)
+			logSampleEstimate(summary, totalCount)
+		} else {
+			summary = cliInstance.QueryPattern(cmd.Context(), p, indexRange)
 		}
-		summary := cliInstance.QueryBatchDomainsStream(domainStream, totalHint)
 
-		if summary.HasFailures() {
-			os.Exit(1)
-		}
+		exitWithSummary(cliInstance, summary)
 	},
 }
 
+// logSampleEstimate 根据抽样结果估计整个范围内可用域名的比例和数量
+func logSampleEstimate(summary *cli.BatchSummary, totalCount uint64) {
+	decided := summary.Available + summary.Registered
+	if decided == 0 {
+		logger.Warn("抽样结果中没有能判断是否可用的域名，无法估计可用比例",
+			"processed", summary.Processed,
+			"failed", summary.Failed,
+			"unknown", summary.Unknown)
+		return
+	}
+	ratio := float64(summary.Available) / float64(decided)
+	logger.Info("抽样估计",
+		"available_ratio", fmt.Sprintf("%.2f%%", ratio*100),
+		"estimated_available", uint64(ratio*float64(totalCount)),
+		"total", totalCount)
+}
+
 func init() {
+	generateCmd.Flags().StringVar(&generateShard, "shard", "", "只查询第 i 份（共 n 份，格式: i/n），多台机器可以各自查询一份")
+	generateCmd.Flags().StringVar(&generateRange, "range", "", "只查询序号在 [start, end) 范围内的组合，格式: start:end，两端均可省略")
+	generateCmd.Flags().StringArrayVar(&wordlists, "wordlist", nil, "词表文件，格式: name=path，模式中用 {@name} 引用，可重复指定（内置词表: "+strings.Join(cli.WordlistNames(), ", ")+"）")
+	generateCmd.Flags().Uint64Var(&sampleSize, "sample", 0, "从所有有效组合中随机抽取 N 个不重复的组合查询，用于估计可用域名的比例")
+	generateCmd.Flags().Int64Var(&sampleSeed, "seed", 0, "随机抽样的种子，相同的种子抽到相同的组合，默认随机")
+	generateCmd.Flags().StringSliceVar(&nameShapes, "shape", nil, "只生成符合辅音/元音结构的名称，C 辅音、V 元音、D 数字，例如 CVCV，逗号分隔多个")
+	generateCmd.Flags().StringSliceVar(&bannedSubstrings, "ban", nil, "名称中不能包含的子串，逗号分隔")
+	generateCmd.Flags().BoolVar(&noDigitsAfterLetters, "no-digits-after-letters", false, "字母之后不能再出现数字，例如跳过 ab1，保留 1ab")
+	generateCmd.Flags().Float64Var(&minScore, "min-score", 0, "只查询综合评分不低于该值的名称（0-100）")
+	generateCmd.Flags().BoolVar(&scoreNames, "score", false, "为结果计算名称质量评分（可读性与键盘距离），CSV 默认增加 score 列")
+	generateCmd.Flags().BoolVar(&sortByScore, "sort-score", false, "查询结束时按评分从高到低写入结果，得到排序后的候选名单；结果暂存在临时文件中，不能与 --checkpoint 一起使用")
+	addCheckpointFlags(generateCmd)
 	rootCmd.AddCommand(generateCmd)
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"gois/cli"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	permuteTypes []string
+	permuteTLDs  []string
+)
+
+var permuteCmd = &cobra.Command{
+	Use:   "permute [domain]",
+	Short: "生成域名的仿冒变体并查询",
+	Long: `生成域名的仿冒（typosquatting）变体并批量查询，用于监控品牌被抢注
+
+变体类型:
+  - omission: 删除一个字符，exmple.com
+  - transposition: 交换相邻字符，exmaple.com
+  - repetition: 重复一个字符，exaample.com
+  - keyboard: 替换或插入键盘上相邻的键，exsmple.com
+  - bitsquatting: 翻转字符的一个比特，dxample.com
+  - homoglyph: 替换为形近字符（包括 IDN），examp1e.com、exаmple.com
+  - hyphenation: 插入连字符，ex-ample.com
+  - vowel-swap: 替换元音，exomple.com
+  - subdomain: 插入点变成子域名形式，ex.ample.com（查询可注册部分 ample.com，与原名称差别过大的跳过）
+  - tld: 替换后缀，example.net
+
+变体归约为可注册域名后按 A-label 去重，不包含原域名，由多种变换得到的域名会标注所有变体类型。
+CSV 输出默认增加 permutation 列，JSON 输出包含 permutation 字段。
+
+示例:
+  gois permute example.com -m simple -o typos.csv
+  gois permute example.com --types omission,homoglyph -c 10
+  gois permute example.com --types tld --tlds com,net,cn,shop
+  gois permute example.com --format ndjson`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := args[0]
+
+		perms, err := cli.Permute(domain, &cli.PermuteConfig{Kinds: permuteTypes, TLDs: permuteTLDs})
+		if err != nil {
+			logger.Error("生成变体失败", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("变体生成完成", "domain", domain, "count", len(perms))
+
+		// CSV 输出默认带上变体类型
+		if len(columns) == 0 {
+			columns = append(slices.Clone(cli.DefaultCSVColumns), "permutation")
+		}
+
+		// 创建 CLI 实例
+		cliInstance, err := createCLI()
+		if err != nil {
+			logger.Error("初始化失败", "error", err)
+			os.Exit(1)
+		}
+		defer cliInstance.Close()
+		cliInstance.SetPermutations(perms)
+
+		domainChan := make(chan string)
+		go func() {
+			defer close(domainChan)
+			for _, perm := range perms {
+				select {
+				case <-cmd.Context().Done():
+					return
+				case domainChan <- perm.Domain:
+				}
+			}
+		}()
+
+		// 批量查询
+		summary := cliInstance.QueryBatchDomainsStream(cmd.Context(), domainChan, int64(len(perms)))
+
+		exitWithSummary(cliInstance, summary)
+	},
+}
+
+func init() {
+	permuteCmd.Flags().StringSliceVar(&permuteTypes, "types", nil, "要生成的变体类型，逗号分隔，默认全部: "+strings.Join(cli.PermutationKinds, ", "))
+	permuteCmd.Flags().StringSliceVar(&permuteTLDs, "tlds", nil, "tld 变体使用的后缀，逗号分隔，默认: "+strings.Join(cli.DefaultPermutationTLDs, ", "))
+	addCheckpointFlags(permuteCmd)
+	rootCmd.AddCommand(permuteCmd)
+}
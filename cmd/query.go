@@ -29,8 +29,14 @@ var queryCmd = &cobra.Command{
 		defer cliInstance.Close()
 
 		// 查询域名
-		result := cliInstance.QuerySingleDomain(domain)
+		result := cliInstance.QuerySingleDomain(cmd.Context(), domain)
 
+		if err := cliInstance.Close(); err != nil {
+			logger.Error("关闭输出文件失败", "error", err)
+		}
+		if cmd.Context().Err() != nil {
+			os.Exit(exitCodeInterrupted)
+		}
 		if !result.Success {
 			os.Exit(1)
 		}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"gois/cli"
+	"gois/whois"
 
 	"github.com/spf13/cobra"
 )
@@ -22,9 +27,27 @@ var (
 	proxy       string
 	outputFile  string
 	mode        string
+	format      string
+	includeRaw  bool
+	columns     []string
+	store       string
 	maxRetries  int
+	retryBudget int
 	concurrency int
 	whoisServer string
+	protocol    string
+	refreshRDAP bool
+	tldCache    bool
+	tldFile     string
+
+	proxyFile     string
+	proxyStrategy string
+	proxyCooldown int
+	serverLimits  []string
+
+	// 批量查询的检查点，仅 batch 和 generate 命令使用
+	checkpointFile string
+	resume         bool
 )
 
 var rootCmd = &cobra.Command{
@@ -35,11 +58,27 @@ var rootCmd = &cobra.Command{
 支持单个域名查询、批量查询、域名生成、并发控制等功能。
 使用 Golang 实现，提供高性能和易用性。`,
 	Version: "1.0.0",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// 查询记录写到标准输出时，日志改写到标准错误，避免混入管道数据
+		if cli.WritesRecordsToStdout(format, outputFile) {
+			logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+				Level: slog.LevelInfo,
+			}))
+		}
+	},
 }
 
+// exitCodeInterrupted 被信号中断时的退出码
+const exitCodeInterrupted = 130
+
 // Execute 执行根命令
+// 收到 SIGINT/SIGTERM 时取消命令的 context，再次收到信号则按默认行为直接退出
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -48,23 +87,87 @@ func Execute() {
 func init() {
 	// 全局标志
 	rootCmd.PersistentFlags().IntVarP(&timeout, "timeout", "t", 10, "查询超时时间（秒）")
-	rootCmd.PersistentFlags().StringVarP(&proxy, "proxy", "p", "", "代理配置，格式: type://addr:port")
+	rootCmd.PersistentFlags().StringVarP(&proxy, "proxy", "p", "", "代理配置，格式: scheme://[user:pass@]host:port，支持 http、https、socks5、socks5h，未指定时读取 ALL_PROXY/HTTPS_PROXY")
 	rootCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "结果输出文件路径")
 	rootCmd.PersistentFlags().StringVarP(&mode, "mode", "m", "normal", "查询模式: normal=完整信息, simple=仅判断可用性")
+	rootCmd.PersistentFlags().StringVar(&format, "format", "", "输出格式: text, csv, json, ndjson，默认 normal 模式为 text、simple 模式为 csv；未指定 -o 时 csv/json/ndjson 写到标准输出")
+	rootCmd.PersistentFlags().StringSliceVar(&columns, "columns", nil, "CSV 输出的列，逗号分隔，可选: "+strings.Join(cli.CSVColumnNames(), ", "))
+	rootCmd.PersistentFlags().StringVar(&store, "store", "", "将结果保存到数据库，格式: sqlite:path.db，重复扫描时按域名更新")
+	rootCmd.PersistentFlags().BoolVar(&includeRaw, "include-raw", false, "json/ndjson 输出中包含原始的注册局与注册商响应")
 	rootCmd.PersistentFlags().IntVarP(&maxRetries, "retries", "r", 3, "查询失败时的重试次数")
+	rootCmd.PersistentFlags().IntVar(&retryBudget, "retry-budget", 0, "整次运行允许的重试总数，0 表示不限制")
 	rootCmd.PersistentFlags().IntVarP(&concurrency, "concurrency", "c", 5, "批量查询时的并发数")
 	rootCmd.PersistentFlags().StringVarP(&whoisServer, "whois-server", "w", "", "指定 WHOIS 服务器（可选）")
+	rootCmd.PersistentFlags().StringVar(&protocol, "protocol", "whois", "查询协议: whois, rdap, auto=优先 RDAP 失败时回退 WHOIS")
+	rootCmd.PersistentFlags().BoolVar(&refreshRDAP, "rdap-refresh", false, "启动时从 IANA 刷新 RDAP 引导数据并缓存到本地")
+	rootCmd.PersistentFlags().BoolVar(&tldCache, "tld-cache", false, "将从 IANA 学习到的 WHOIS 服务器保存到用户缓存文件，并在后续运行中优先使用")
+	rootCmd.PersistentFlags().StringVar(&proxyFile, "proxy-file", "", "代理列表文件，每行一个代理地址，WHOIS 查询在列表中轮换")
+	rootCmd.PersistentFlags().StringVar(&proxyStrategy, "proxy-strategy", "round-robin", "代理轮换策略: round-robin=轮询, lru=按 WHOIS 服务器选择最久未使用的代理")
+	rootCmd.PersistentFlags().IntVar(&proxyCooldown, "proxy-cooldown", 60, "代理连接失败后的冷却时间（秒）")
+	rootCmd.PersistentFlags().StringArrayVar(&serverLimits, "server-limit", nil, "WHOIS 服务器限速，格式: server=每秒查询数[,突发数[,最大并发]]，server 为 default 时作用于所有未单独配置的服务器，可重复指定")
+	rootCmd.PersistentFlags().StringVar(&tldFile, "tld-file", "", "TLD 服务器覆盖文件（JSON 或 YAML，顶层为 tld: server 映射），默认使用用户配置目录下的 gois/tlds.json")
 }
 
 // createCLI 创建 CLI 实例
 func createCLI() (*cli.CLI, error) {
+	config, err := newQueryConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cli.NewCLI(config)
+}
+
+// newQueryConfig 根据全局标志构造查询配置
+func newQueryConfig() (*cli.QueryConfig, error) {
 	config := &cli.QueryConfig{
 		Timeout:     time.Duration(timeout) * time.Second,
 		OutputFile:  outputFile,
 		Mode:        mode,
+		Format:      format,
+		IncludeRaw:  includeRaw,
+		Columns:     columns,
+		Store:       store,
 		MaxRetries:  maxRetries,
+		RetryBudget: retryBudget,
 		Concurrency: concurrency,
 		WhoisServer: whoisServer,
+		Protocol:    protocol,
+		RefreshRDAP: refreshRDAP,
+		TLDCache:    tldCache,
+		TLDFile:     tldFile,
+
+		ProxyFile:     proxyFile,
+		ProxyStrategy: proxyStrategy,
+		ProxyCooldown: time.Duration(proxyCooldown) * time.Second,
+
+		Checkpoint:  checkpointFile,
+		Score:       scoreNames || minScore > 0,
+		SortByScore: sortByScore,
+		Resume:      resume,
+	}
+
+	if resume && checkpointFile == "" {
+		return nil, fmt.Errorf("--resume 需要同时指定 --checkpoint")
+	}
+	// 排序输出在结束时才写入，中断后检查点记录的进度会跳过没有写出的结果
+	if sortByScore && checkpointFile != "" {
+		return nil, fmt.Errorf("--sort-score 不能与 --checkpoint 一起使用")
+	}
+
+	// 解析服务器限速配置
+	if len(serverLimits) > 0 {
+		config.ServerLimits = make(map[string]whois.ServerLimit, len(serverLimits))
+		for _, value := range serverLimits {
+			server, spec, ok := strings.Cut(value, "=")
+			if !ok || server == "" {
+				return nil, fmt.Errorf("无效的服务器限速配置: %s (需要格式: server=rate[,burst[,inflight]])", value)
+			}
+			limit, err := whois.ParseServerLimit(spec)
+			if err != nil {
+				return nil, err
+			}
+			config.ServerLimits[strings.ToLower(server)] = limit
+		}
 	}
 
 	// 解析代理配置
@@ -76,8 +179,38 @@ func createCLI() (*cli.CLI, error) {
 		if proxyURL.Scheme == "" || proxyURL.Host == "" {
 			return nil, fmt.Errorf("无效的代理格式: %s (需要格式: scheme://host:port)", proxy)
 		}
+		if err := whois.ValidateProxyURL(proxyURL); err != nil {
+			return nil, err
+		}
+		config.Proxy = proxyURL
+	} else {
+		// 未指定代理时使用 ALL_PROXY / HTTPS_PROXY 环境变量
+		proxyURL, err := whois.ProxyFromEnvironment()
+		if err != nil {
+			return nil, err
+		}
 		config.Proxy = proxyURL
 	}
 
-	return cli.NewCLI(config)
+	return config, nil
+}
+
+// addCheckpointFlags 为批量查询命令添加检查点参数
+func addCheckpointFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&checkpointFile, "checkpoint", "", "进度记录文件，中断后配合 --resume 从上次的位置继续")
+	cmd.Flags().BoolVar(&resume, "resume", false, "跳过检查点中已完成的域名，并追加到已有的输出文件")
+}
+
+// exitWithSummary 关闭 CLI 资源后根据批量统计设置退出码
+func exitWithSummary(cliInstance *cli.CLI, summary *cli.BatchSummary) {
+	if err := cliInstance.Close(); err != nil {
+		logger.Error("关闭输出文件失败", "error", err)
+	}
+
+	switch {
+	case summary.Interrupted:
+		os.Exit(exitCodeInterrupted)
+	case summary.HasFailures():
+		os.Exit(1)
+	}
 }
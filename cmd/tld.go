@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"gois/cli"
+	"gois/whois"
+
+	"github.com/spf13/cobra"
+)
+
+var tldCmd = &cobra.Command{
+	Use:   "tld",
+	Short: "管理 TLD 到 WHOIS 服务器的映射",
+	Long: `管理 TLD 到 WHOIS 服务器的映射
+
+内嵌的映射在构建时确定，tld refresh 生成的映射（用户缓存目录下的 gois/tlds-iana.json）存在时取代内嵌映射。
+可以通过覆盖文件（--tld-file，默认为用户配置目录下的 gois/tlds.json）为特定 TLD 指定服务器，
+覆盖文件优先级最高，刷新不会改动它。覆盖文件支持 JSON 和 YAML（.yaml/.yml）格式。
+
+示例:
+  gois tld list
+  gois tld show com
+  gois tld set io whois.nic.io
+  gois tld refresh -c 10
+  gois tld diff`,
+}
+
+var tldListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出所有 TLD 及其 WHOIS 服务器",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		registry := loadTLDRegistry()
+		printTLDMap(registry.Snapshot())
+	},
+}
+
+var tldShowCmd = &cobra.Command{
+	Use:   "show <tld>",
+	Short: "显示指定 TLD 的 WHOIS 服务器",
+	Long: `显示指定 TLD 或公共后缀的 WHOIS 服务器
+
+与查询时相同，后缀没有单独的映射时依次尝试更短的后缀，例如 co.uk 没有映射时使用 uk 的服务器。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tld := whois.NormalizeTLD(args[0])
+		registry := loadTLDRegistry()
+
+		matched, server, ok := registry.LookupSuffix(tld)
+		if !ok {
+			logger.Error("未找到 TLD 的 WHOIS 服务器", "tld", tld)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s\t%s\n", tld, server)
+		if matched != tld {
+			fmt.Printf("使用 %s 的映射\n", matched)
+		}
+
+		embedded, err := whois.EmbeddedTLDs()
+		if err == nil {
+			if original, ok := embedded[matched]; ok && original != server {
+				fmt.Printf("内嵌服务器: %s\n", original)
+			}
+		}
+	},
+}
+
+var tldSetCmd = &cobra.Command{
+	Use:   "set <tld> <server>",
+	Short: "在覆盖文件中设置 TLD 的 WHOIS 服务器",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		tld := whois.NormalizeTLD(args[0])
+		server := args[1]
+
+		config, err := newQueryConfig()
+		if err != nil {
+			logger.Error("初始化失败", "error", err)
+			os.Exit(1)
+		}
+		path, err := cli.TLDFilePath(config)
+		if err != nil {
+			logger.Error("初始化失败", "error", err)
+			os.Exit(1)
+		}
+
+		overrides, err := whois.LoadTLDFile(path)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger.Error("读取 TLD 覆盖文件失败", "error", err)
+				os.Exit(1)
+			}
+			overrides = make(map[string]string)
+		}
+
+		overrides[tld] = server
+		if err := whois.SaveTLDFile(path, overrides); err != nil {
+			logger.Error("写入 TLD 覆盖文件失败", "error", err)
+			os.Exit(1)
+		}
+
+		logger.Info("已设置 TLD 的 WHOIS 服务器", "tld", tld, "server", server, "file", path)
+	},
+}
+
+var tldRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "从 IANA 重新生成 TLD 映射",
+	Long: `从 IANA 重新生成 TLD 到 WHOIS 服务器的映射
+
+结果写入 -o 指定的文件，未指定时写入用户缓存目录下的 gois/tlds-iana.json，
+之后的运行用它取代内嵌映射。用户覆盖文件（tld set 写入的文件）不会被改动，其中的设置仍然优先。
+二级后缀（如 gov.uk）无法从 IANA 获取，会沿用现有映射。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := newQueryConfig()
+		if err != nil {
+			logger.Error("初始化失败", "error", err)
+			os.Exit(1)
+		}
+
+		path := config.OutputFile
+		if path == "" {
+			path, err = cli.TLDRefreshPath()
+			if err != nil {
+				logger.Error("初始化失败", "error", err)
+				os.Exit(1)
+			}
+		}
+
+		// 以内嵌映射或上次刷新的结果为基础，不包含用户覆盖，避免把覆盖写进刷新结果
+		registry, err := whois.NewTLDRegistry()
+		if err != nil {
+			logger.Error("初始化失败", "error", err)
+			os.Exit(1)
+		}
+		if err := cli.ConfigureBaseTLDRegistry(registry); err != nil {
+			logger.Error("初始化失败", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("正在从 IANA 刷新 TLD 映射", "concurrency", config.Concurrency)
+
+		refreshed, err := cli.RefreshTLDServers(cmd.Context(), config, registry.Snapshot(), logger)
+		if err != nil {
+			logger.Error("刷新 TLD 映射失败", "error", err)
+			os.Exit(1)
+		}
+
+		if err := whois.SaveTLDFile(path, refreshed); err != nil {
+			logger.Error("写入 TLD 映射失败", "error", err)
+			os.Exit(1)
+		}
+
+		logger.Info("TLD 映射刷新完成", "count", len(refreshed), "file", path)
+	},
+}
+
+var tldDiffCmd = &cobra.Command{
+	Use:   "diff [file]",
+	Short: "列出与内嵌映射相比服务器发生变化的 TLD",
+	Long: `列出与内嵌映射相比服务器发生变化的 TLD
+
+未指定文件时比较当前生效的映射（内嵌数据或刷新结果 + 缓存 + 覆盖文件）；
+指定文件时直接比较文件中的完整映射，例如 tld refresh 的输出，文件中没有的 TLD 显示为删除。`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		embedded, err := whois.EmbeddedTLDs()
+		if err != nil {
+			logger.Error("加载内嵌 TLD 映射失败", "error", err)
+			os.Exit(1)
+		}
+
+		var current map[string]string
+		if len(args) == 1 {
+			// 不合并到内嵌映射之上，否则文件中删除的 TLD 不会显示出来
+			current, err = whois.LoadTLDFile(args[0])
+			if err != nil {
+				logger.Error("读取 TLD 文件失败", "error", err)
+				os.Exit(1)
+			}
+		} else {
+			current = loadTLDRegistry().Snapshot()
+		}
+
+		for _, change := range whois.DiffTLDs(embedded, current) {
+			switch {
+			case change.Old == "":
+				fmt.Printf("+ %s\t%s\n", change.TLD, change.New)
+			case change.New == "":
+				fmt.Printf("- %s\t%s\n", change.TLD, change.Old)
+			default:
+				fmt.Printf("~ %s\t%s -> %s\n", change.TLD, change.Old, change.New)
+			}
+		}
+	},
+}
+
+// loadTLDRegistry 加载当前生效的 TLD 注册表，失败时退出
+func loadTLDRegistry() *whois.TLDRegistry {
+	config, err := newQueryConfig()
+	if err != nil {
+		logger.Error("初始化失败", "error", err)
+		os.Exit(1)
+	}
+
+	registry, err := whois.NewTLDRegistry()
+	if err != nil {
+		logger.Error("初始化失败", "error", err)
+		os.Exit(1)
+	}
+	if err := cli.ConfigureTLDRegistry(registry, config); err != nil {
+		logger.Error("初始化失败", "error", err)
+		os.Exit(1)
+	}
+
+	return registry
+}
+
+// printTLDMap 按 TLD 排序输出映射
+func printTLDMap(tlds map[string]string) {
+	keys := make([]string, 0, len(tlds))
+	for tld := range tlds {
+		keys = append(keys, tld)
+	}
+	sort.Strings(keys)
+
+	for _, tld := range keys {
+		fmt.Printf("%s\t%s\n", tld, tlds[tld])
+	}
+}
+
+func init() {
+	tldCmd.AddCommand(tldListCmd, tldShowCmd, tldSetCmd, tldRefreshCmd, tldDiffCmd)
+	rootCmd.AddCommand(tldCmd)
+}
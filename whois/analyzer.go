@@ -7,17 +7,28 @@ import (
 
 // DomainInfo 域名信息
 type DomainInfo struct {
-	Status         string   `json:"status"` // available, registered, unknown
+	Domain         string   `json:"domain,omitempty"`         // A-label 形式
+	UnicodeDomain  string   `json:"unicode_domain,omitempty"` // Unicode 形式
+	Status         string   `json:"status"`                   // available, registered, unknown, rate_limited
 	Registrar      string   `json:"registrar,omitempty"`
 	CreationDate   string   `json:"creation_date,omitempty"`
 	ExpirationDate string   `json:"expiration_date,omitempty"`
 	NameServers    []string `json:"name_servers,omitempty"`
 }
 
+// 域名状态
+const (
+	StatusAvailable   = "available"
+	StatusRegistered  = "registered"
+	StatusUnknown     = "unknown"
+	StatusRateLimited = "rate_limited"
+)
+
 // Analyzer WHOIS 结果分析器
 type Analyzer struct {
 	availableKeywords  []string
 	registeredKeywords []string
+	rateLimitKeywords  []string
 	// 预编译的正则表达式，避免重复编译
 	registrarRegexps      []*regexp.Regexp
 	creationDateRegexps   []*regexp.Regexp
@@ -95,6 +106,24 @@ func NewAnalyzer() *Analyzer {
 			"创建时间",
 			"到期时间",
 		},
+		rateLimitKeywords: []string{
+			"rate limit exceeded",
+			"limit exceeded",
+			"limit reached",
+			"quota exceeded",
+			"too many requests",
+			"too many queries",
+			"exceeded the maximum allowable number",
+			"exceeded your query limit",
+			"maximum number of queries",
+			"try again later",
+			"access denied",
+			"you have been banned",
+			"has been blocked",
+			"blacklisted",
+			"查询过于频繁",
+			"超过查询限制",
+		},
 		registrarRegexps:      compileRegexps(registrarPatterns),
 		creationDateRegexps:   compileRegexps(creationDatePatterns),
 		expirationDateRegexps: compileRegexps(expirationDatePatterns),
@@ -102,52 +131,101 @@ func NewAnalyzer() *Analyzer {
 	}
 }
 
-// GetDomainStatus 获取域名状态：available（可用）、registered（已注册）、unknown（未知）
+// GetDomainStatus 获取域名状态：available（可用）、registered（已注册）、unknown（未知）、rate_limited（被限速或拒绝）
 func (a *Analyzer) GetDomainStatus(result *QueryResult) string {
 	if result == nil {
-		return "unknown"
+		return StatusUnknown
+	}
+	if result.Info != nil {
+		return result.Info.Status
 	}
 
 	// 合并两个结果
 	combined := strings.ToLower(result.RegistryResult + "\n" + result.RegistrarResult)
 
 	if strings.TrimSpace(combined) == "" {
-		return "unknown"
+		return StatusUnknown
 	}
 
 	// 检查可用关键词
-	availableScore := 0
-	for _, keyword := range a.availableKeywords {
-		if strings.Contains(combined, strings.ToLower(keyword)) {
-			availableScore++
-		}
-	}
+	availableScore := countKeywords(combined, a.availableKeywords)
 
 	// 检查已注册关键词
-	registeredScore := 0
-	for _, keyword := range a.registeredKeywords {
-		if strings.Contains(combined, strings.ToLower(keyword)) {
-			registeredScore++
-		}
+	registeredScore := countKeywords(combined, a.registeredKeywords)
+
+	// 限速或拒绝响应既不包含注册信息也不包含可用标记，
+	// 只看响应开头或很短的响应，避免把正文中的使用条款当成限速提示
+	if registeredScore == 0 && availableScore == 0 &&
+		(a.hasRateLimitNotice(result.RegistryResult) || a.hasRateLimitNotice(result.RegistrarResult)) {
+		return StatusRateLimited
 	}
 
 	// 如果有明确的可用标记，优先判断为可用
 	if availableScore > 0 && registeredScore == 0 {
-		return "available"
+		return StatusAvailable
 	}
 
 	// 如果有明确的已注册标记
 	if registeredScore > 0 {
-		return "registered"
+		return StatusRegistered
 	}
 
 	// 如果两者都有，以已注册为准（保守判断）
 	if availableScore > 0 {
-		return "registered"
+		return StatusRegistered
 	}
 
 	// 关键词均不存在，返回未知
-	return "unknown"
+	return StatusUnknown
+}
+
+// IsRateLimited 判断 WHOIS 响应是否为限速、配额用尽或拒绝访问
+// 包含注册信息或可用标记的响应不算限速，即使使用条款里提到了 "access denied" 之类的词
+func (a *Analyzer) IsRateLimited(response string) bool {
+	lower := strings.ToLower(response)
+	if countKeywords(lower, a.registeredKeywords) > 0 || countKeywords(lower, a.availableKeywords) > 0 {
+		return false
+	}
+	return a.hasRateLimitNotice(response)
+}
+
+// 限速提示只在响应开头查找，很短的响应查找全文
+const (
+	rateLimitShortResponse = 512 // 不超过该长度（字节）的响应查找全文
+	rateLimitHeaderLines   = 5   // 较长的响应只查找前几个非空行
+)
+
+// hasRateLimitNotice 判断响应开头或很短的响应中是否有限速提示
+func (a *Analyzer) hasRateLimitNotice(response string) bool {
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return false
+	}
+	if len(response) <= rateLimitShortResponse {
+		return countKeywords(strings.ToLower(response), a.rateLimitKeywords) > 0
+	}
+
+	var header []string
+	for line := range strings.Lines(response) {
+		if line = strings.TrimSpace(line); line != "" {
+			header = append(header, line)
+			if len(header) == rateLimitHeaderLines {
+				break
+			}
+		}
+	}
+	return countKeywords(strings.ToLower(strings.Join(header, "\n")), a.rateLimitKeywords) > 0
+}
+
+// countKeywords 统计 text 中出现的关键词数量，text 需为小写
+func countKeywords(text string, keywords []string) int {
+	score := 0
+	for _, keyword := range keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			score++
+		}
+	}
+	return score
 }
 
 // ExtractRegistrar 提取注册商信息
@@ -155,6 +233,9 @@ func (a *Analyzer) ExtractRegistrar(result *QueryResult) string {
 	if result == nil {
 		return ""
 	}
+	if result.Info != nil {
+		return result.Info.Registrar
+	}
 
 	combined := result.RegistrarResult + "\n" + result.RegistryResult
 
@@ -173,6 +254,9 @@ func (a *Analyzer) ExtractCreationDate(result *QueryResult) string {
 	if result == nil {
 		return ""
 	}
+	if result.Info != nil {
+		return result.Info.CreationDate
+	}
 
 	combined := result.RegistrarResult + "\n" + result.RegistryResult
 
@@ -191,6 +275,9 @@ func (a *Analyzer) ExtractExpirationDate(result *QueryResult) string {
 	if result == nil {
 		return ""
 	}
+	if result.Info != nil {
+		return result.Info.ExpirationDate
+	}
 
 	combined := result.RegistrarResult + "\n" + result.RegistryResult
 
@@ -209,6 +296,9 @@ func (a *Analyzer) ExtractNameServers(result *QueryResult) []string {
 	if result == nil {
 		return nil
 	}
+	if result.Info != nil {
+		return result.Info.NameServers
+	}
 
 	combined := result.RegistrarResult + "\n" + result.RegistryResult
 
@@ -234,11 +324,16 @@ func (a *Analyzer) ExtractNameServers(result *QueryResult) []string {
 
 // GetDomainInfo 提取域名的完整信息
 func (a *Analyzer) GetDomainInfo(result *QueryResult) *DomainInfo {
-	return &DomainInfo{
+	info := &DomainInfo{
 		Status:         a.GetDomainStatus(result),
 		Registrar:      a.ExtractRegistrar(result),
 		CreationDate:   a.ExtractCreationDate(result),
 		ExpirationDate: a.ExtractExpirationDate(result),
 		NameServers:    a.ExtractNameServers(result),
 	}
+	if result != nil {
+		info.Domain = result.Domain
+		info.UnicodeDomain = result.UnicodeDomain
+	}
+	return info
 }
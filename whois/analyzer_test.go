@@ -0,0 +1,101 @@
+package whois
+
+import (
+	"strings"
+	"testing"
+)
+
+// 很长的使用条款，模拟注册局在响应末尾附加的法律声明
+var whoisTermsOfUse = strings.Repeat("NOTICE: The expiration date displayed in this record is the date the registrar's sponsorship of the domain name registration in the registry is currently set to expire. ", 4) +
+	"\nBy submitting a WHOIS query, you agree to abide by the following terms of use. Access denied to anyone who uses automated means to collect data; " +
+	"excessive querying will result in rate limit exceeded responses, please try again later.\n"
+
+func TestGetDomainStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		registry  string
+		registrar string
+		want      string
+	}{
+		{
+			name:     "registered",
+			registry: "   Domain Name: EXAMPLE.COM\n   Registrar: Example Registrar, Inc.\n   Creation Date: 1995-08-14T04:00:00Z\n   Name Server: A.IANA-SERVERS.NET\n" + whoisTermsOfUse,
+			want:     StatusRegistered,
+		},
+		{
+			name:     "available with terms mentioning rate limits",
+			registry: "No match for \"NOTTAKEN-EXAMPLE.COM\".\n>>> Last update of whois database: 2026-10-16T00:00:00Z <<<\n\n" + whoisTermsOfUse,
+			want:     StatusAvailable,
+		},
+		{
+			name:     "available short response mentioning try again later",
+			registry: "Domain not found. If you just registered it, try again later.\n",
+			want:     StatusAvailable,
+		},
+		{
+			name:     "short rate limit response",
+			registry: "WHOIS LIMIT EXCEEDED - SEE WWW.PIR.ORG/WHOIS FOR DETAILS\n",
+			want:     StatusRateLimited,
+		},
+		{
+			name:     "denic access control",
+			registry: "% Error: 55000000002 Connection refused; access control limit reached.\n",
+			want:     StatusRateLimited,
+		},
+		{
+			name:     "rate limit notice in header of long response",
+			registry: "% Too many queries from your IP address, please try again later.\n%\n" + strings.Repeat("% This server is provided for informational purposes only.\n", 20),
+			want:     StatusRateLimited,
+		},
+		{
+			name:     "rate limit keyword only in body of long response",
+			registry: strings.Repeat("% This server is provided for informational purposes only.\n", 20) + "% Abuse results in access denied.\n",
+			want:     StatusUnknown,
+		},
+		{
+			name:      "registrar rate limited but registry registered",
+			registry:  "Domain Name: EXAMPLE.COM\nRegistrar: Example Registrar, Inc.\n",
+			registrar: "Quota exceeded\n",
+			want:      StatusRegistered,
+		},
+		{
+			name: "empty",
+			want: StatusUnknown,
+		},
+	}
+
+	analyzer := NewAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &QueryResult{RegistryResult: tt.registry, RegistrarResult: tt.registrar}
+			if got := analyzer.GetDomainStatus(result); got != tt.want {
+				t.Errorf("GetDomainStatus = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     bool
+	}{
+		{"short limit response", "Your connection limit exceeded. Please slow down and try again later.\n", true},
+		{"chinese", "查询过于频繁，请稍后再试\n", true},
+		{"blocked header", "% You have been banned for abuse\n" + strings.Repeat("%\n% informational text\n", 40), true},
+		{"not found with terms", "NOT FOUND\n" + whoisTermsOfUse, false},
+		{"registered with terms", "Registrar: Example Registrar, Inc.\n" + whoisTermsOfUse, false},
+		{"terms only in body", strings.Repeat("% informational text\n", 40) + whoisTermsOfUse, false},
+		{"empty", "", false},
+	}
+
+	analyzer := NewAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzer.IsRateLimited(tt.response); got != tt.want {
+				t.Errorf("IsRateLimited = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,20 @@
 package whois
 
 import (
-	"bufio"
-	"fmt"
+	"context"
+	"errors"
+	"io"
 	"net"
 	"net/url"
+	"os"
 	"regexp"
 	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
-	"golang.org/x/net/proxy"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/encoding/unicode"
 )
 
 const (
@@ -20,10 +22,27 @@ const (
 	ianaWhoisServer  = "whois.iana.org"
 )
 
+// 查询协议
+const (
+	ProtocolWhois = "whois"
+	ProtocolRDAP  = "rdap"
+)
+
 // QueryResult WHOIS 查询结果
 type QueryResult struct {
+	// Domain 实际查询的可注册域名（A-label 形式）
+	Domain string `json:"domain"`
+	// UnicodeDomain 可注册域名的 Unicode 形式
+	UnicodeDomain   string `json:"unicode_domain"`
 	RegistryResult  string `json:"registry_result"`
 	RegistrarResult string `json:"registrar_result"`
+	// RegistryServer、RegistrarServer 实际查询的注册局与注册商服务器，RDAP 为请求地址
+	RegistryServer  string `json:"registry_server"`
+	RegistrarServer string `json:"registrar_server,omitempty"`
+	// Protocol 结果来源协议: whois 或 rdap
+	Protocol string `json:"protocol"`
+	// Info 由 RDAP JSON 直接解析出的结构化信息，WHOIS 文本结果为 nil
+	Info *DomainInfo `json:"info,omitempty"`
 }
 
 // Client WHOIS 客户端
@@ -31,13 +50,27 @@ type Client struct {
 	timeout  time.Duration
 	proxy    *url.URL
 	registry *TLDRegistry
+	// proxyPool 设置后每次连接从代理池中选择代理，优先于 proxy
+	proxyPool *ProxyPool
 	// 预编译的正则表达式，避免重复编译
 	ianaWhoisRegexp  *regexp.Regexp
 	registrarRegexps []*regexp.Regexp
+	// ianaLookups 合并同一 TLD 的并发 IANA 查询
+	ianaLookups callGroup
+	// limiter 按 WHOIS 服务器限速并限制并发
+	limiter *rateLimiter
+	// analyzer 用于识别限速响应
+	analyzer *Analyzer
 }
 
 // NewClient 创建一个新的 WHOIS 客户端
 func NewClient(timeout time.Duration, proxyURL *url.URL) (*Client, error) {
+	if proxyURL != nil {
+		if err := ValidateProxyURL(proxyURL); err != nil {
+			return nil, err
+		}
+	}
+
 	registry, err := NewTLDRegistry()
 	if err != nil {
 		return nil, err
@@ -59,13 +92,20 @@ func NewClient(timeout time.Duration, proxyURL *url.URL) (*Client, error) {
 		registry:         registry,
 		ianaWhoisRegexp:  regexp.MustCompile(`(?mi)^.*whois:.*$`),
 		registrarRegexps: registrarRegexps,
+		limiter:          newRateLimiter(),
+		analyzer:         NewAnalyzer(),
 	}, nil
 }
 
 // Fetch 查询域名的 WHOIS 信息
 func (c *Client) Fetch(domain string, whoisServer string) (*QueryResult, error) {
-	// 域名标准化
-	normalizedDomain, tld, err := c.parseDomain(domain)
+	return c.FetchContext(context.Background(), domain, whoisServer)
+}
+
+// FetchContext 查询域名的 WHOIS 信息，ctx 取消时会中断拨号、读写以及注册商/IANA 的后续查询
+func (c *Client) FetchContext(ctx context.Context, domain string, whoisServer string) (*QueryResult, error) {
+	// 域名标准化，提取可注册域名及其公共后缀
+	normalizedDomain, suffix, err := parseDomain(domain)
 	if err != nil {
 		return nil, err
 	}
@@ -75,76 +115,102 @@ func (c *Client) Fetch(domain string, whoisServer string) (*QueryResult, error)
 	if whoisServer != "" {
 		selectedServer = whoisServer
 	} else {
-		selectedServer, err = c.findWhoisServer(tld)
+		selectedServer, err = c.findWhoisServer(ctx, suffix)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// 查询注册局 WHOIS 服务器
-	registryResult, err := c.query(normalizedDomain, selectedServer)
+	registryResult, err := c.query(ctx, normalizedDomain, selectedServer)
 	if err != nil {
 		return nil, err
 	}
+	if c.analyzer.IsRateLimited(registryResult) {
+		return nil, &RateLimitedError{Server: selectedServer, Query: normalizedDomain}
+	}
 
 	// 尝试从注册局响应中提取注册商 WHOIS 服务器
 	var registrarResult string
 	registrarServer := c.extractRegistrarServer(registryResult)
 	if registrarServer != "" {
-		registrarResult, _ = c.query(normalizedDomain, registrarServer)
+		registrarResult, _ = c.query(ctx, normalizedDomain, registrarServer)
+		// 注册商限速时丢弃其响应，只保留注册局结果
+		if c.analyzer.IsRateLimited(registrarResult) {
+			registrarResult = ""
+		}
+	}
+
+	// 注册商查询失败时忽略错误，但取消必须向上传递
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	return &QueryResult{
+	result := &QueryResult{
+		Domain:          normalizedDomain,
+		UnicodeDomain:   toUnicode(normalizedDomain),
 		RegistryResult:  registryResult,
 		RegistrarResult: registrarResult,
-	}, nil
-}
-
-// parseDomain 解析域名，提取标准化的域名和 TLD
-func (c *Client) parseDomain(domain string) (string, string, error) {
-	// 去除协议
-	domain = strings.TrimPrefix(domain, "http://")
-	domain = strings.TrimPrefix(domain, "https://")
-	domain = strings.ToLower(strings.TrimSpace(domain))
-
-	// 去除路径
-	if idx := strings.Index(domain, "/"); idx != -1 {
-		domain = domain[:idx]
+		RegistryServer:  selectedServer,
+		Protocol:        ProtocolWhois,
 	}
+	if registrarResult != "" {
+		result.RegistrarServer = registrarServer
+	}
+	return result, nil
+}
 
-	// 提取 TLD
-	parts := strings.Split(domain, ".")
-	if len(parts) < 2 {
-		return "", "", &BadDomainError{Domain: domain}
+// findWhoisServer 查找公共后缀对应的 WHOIS 服务器
+// 从最长后缀开始尝试，例如 gov.uk 先查 gov.uk 再查 uk，使二级注册局可以使用独立的服务器
+func (c *Client) findWhoisServer(ctx context.Context, suffix string) (string, error) {
+	// 先从本地注册表查找
+	if _, server, ok := c.registry.LookupSuffix(suffix); ok {
+		return server, nil
 	}
 
-	// 获取顶级域名（最后一部分）
-	tld := parts[len(parts)-1]
+	// 如果本地没有，从 IANA 查询顶级域名
+	candidates := suffixCandidates(suffix)
+	return c.fetchWhoisServerFromIANA(ctx, candidates[len(candidates)-1])
+}
 
-	if tld == "" {
-		return "", "", &BadDomainError{Domain: domain}
-	}
+// Registry 返回客户端使用的 TLD 注册表
+func (c *Client) Registry() *TLDRegistry {
+	return c.registry
+}
 
-	return domain, tld, nil
+// fetchWhoisServerFromIANA 从 IANA 查询 TLD 的 WHOIS 服务器，同一 TLD 的并发查询只发送一次
+func (c *Client) fetchWhoisServerFromIANA(ctx context.Context, tld string) (string, error) {
+	return c.ianaLookups.Do(ctx, tld, func() (string, error) {
+		// 等待期间其他查询可能已经写入注册表
+		if server, ok := c.registry.GetWhoisServer(tld); ok {
+			return server, nil
+		}
+		return c.lookupIANA(ctx, tld)
+	})
 }
 
-// findWhoisServer 查找 TLD 对应的 WHOIS 服务器
-func (c *Client) findWhoisServer(tld string) (string, error) {
-	// 先从本地注册表查找
-	if server, ok := c.registry.GetWhoisServer(tld); ok {
-		return server, nil
+// lookupIANA 向 IANA 查询 TLD 的 WHOIS 服务器并记录到注册表
+func (c *Client) lookupIANA(ctx context.Context, tld string) (string, error) {
+	server, err := c.LookupIANAWhoisServer(ctx, tld)
+	if err != nil {
+		return "", err
 	}
 
-	// 如果本地没有，从 IANA 查询
-	return c.fetchWhoisServerFromIANA(tld)
+	// 缓存结果，缓存文件写入失败不影响本次查询
+	_ = c.registry.LearnWhoisServer(tld, server)
+
+	return server, nil
 }
 
-// fetchWhoisServerFromIANA 从 IANA 查询 TLD 的 WHOIS 服务器
-func (c *Client) fetchWhoisServerFromIANA(tld string) (string, error) {
-	result, err := c.query(tld, ianaWhoisServer)
+// LookupIANAWhoisServer 直接向 IANA 查询 TLD 的 WHOIS 服务器，不读写注册表
+func (c *Client) LookupIANAWhoisServer(ctx context.Context, tld string) (string, error) {
+	result, err := c.query(ctx, toASCII(tld), ianaWhoisServer)
 	if err != nil {
 		return "", err
 	}
+	if c.analyzer.IsRateLimited(result) {
+		return "", &RateLimitedError{Server: ianaWhoisServer, Query: tld}
+	}
 
 	// 提取 WHOIS 服务器
 	matches := c.ianaWhoisRegexp.FindStringSubmatch(result)
@@ -158,9 +224,9 @@ func (c *Client) fetchWhoisServerFromIANA(tld string) (string, error) {
 	}
 
 	server := strings.TrimSpace(parts[1])
-
-	// 缓存结果
-	c.registry.SetWhoisServer(tld, server)
+	if server == "" {
+		return "", &NoWhoisServerFoundError{TLD: tld}
+	}
 
 	return server, nil
 }
@@ -185,9 +251,9 @@ func (c *Client) extractRegistrarServer(response string) string {
 }
 
 // query 执行 WHOIS 查询
-func (c *Client) query(domain, server string) (string, error) {
-	// 建立连接
-	conn, err := c.dial(server, defaultWhoisPort)
+func (c *Client) query(ctx context.Context, domain, server string) (string, error) {
+	// 等待服务器的限速令牌和并发槽位
+	release, err := c.limiter.acquire(ctx, server)
 	if err != nil {
 		return "", &SocketError{
 			Server: server,
@@ -195,10 +261,27 @@ func (c *Client) query(domain, server string) (string, error) {
 			Err:    err,
 		}
 	}
+	defer release()
+
+	// 建立连接
+	conn, err := c.dial(ctx, server, defaultWhoisPort)
+	if err != nil {
+		return "", socketError(ctx, server, domain, PhaseConnect, err)
+	}
 	defer conn.Close()
 
-	// 设置超时
-	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+	// ctx 取消时立即让阻塞中的读写返回
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	// 设置超时，ctx 的截止时间更早时以 ctx 为准
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
 		return "", &SocketError{
 			Server: server,
 			Query:  domain,
@@ -209,132 +292,176 @@ func (c *Client) query(domain, server string) (string, error) {
 	// 发送查询
 	query := domain + "\r\n"
 	if _, err := conn.Write([]byte(query)); err != nil {
-		return "", &SocketError{
-			Server: server,
-			Query:  domain,
-			Err:    err,
-		}
+		return "", socketError(ctx, server, domain, PhaseWrite, err)
 	}
 
 	// 读取响应
-	var result strings.Builder
-	scanner := bufio.NewScanner(conn)
-	// 使用较小的初始缓冲区和最大缓冲区，避免高并发时占用过多内存
-	// 大多数WHOIS响应不会超过256KB
-	scanner.Buffer(make([]byte, 4096), 256*1024) // 256KB max buffer
+	data, err := readResponse(conn)
+	if err != nil {
+		// 已经收到部分数据后连接中断或响应过大，响应不完整
+		if len(data) > 0 && ctx.Err() == nil {
+			return "", &TruncatedResponseError{
+				Server:   server,
+				Query:    domain,
+				Received: len(data),
+				Err:      err,
+			}
+		}
+		return "", socketError(ctx, server, domain, PhaseRead, err)
+	}
+
+	return decodeResponse(data), nil
+}
+
+// socketError 将网络错误转换为对应的错误类型
+// ctx 取消时返回包装 ctx.Err() 的 SocketError，便于调用方用 errors.Is 识别取消
+func socketError(ctx context.Context, server, domain, phase string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &SocketError{Server: server, Query: domain, Err: ctxErr}
+	}
 
-	for scanner.Scan() {
-		result.WriteString(scanner.Text())
-		result.WriteString("\n")
+	// 代理本身的错误不按 WHOIS 服务器的网络错误分类
+	var proxyErr *ProxyError
+	if errors.As(err, &proxyErr) {
+		return &SocketError{Server: server, Query: domain, Err: err}
 	}
 
-	if err := scanner.Err(); err != nil {
-		// 尝试使用不同的编码
-		return c.readWithEncoding(conn)
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return &DNSError{Server: server, Query: domain, Err: err}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return &ConnectionRefusedError{Server: server, Query: domain, Err: err}
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &SocketTimeoutError{Server: server, Query: domain, Phase: phase, Err: err}
 	}
+	return &SocketError{Server: server, Query: domain, Err: err}
+}
+
+// 响应最大读取大小，避免内存无限增长
+const maxResponseSize = 512 * 1024
+
+// errResponseTooLarge 响应超过 maxResponseSize，剩余部分没有读取
+var errResponseTooLarge = errors.New("response exceeds maximum size")
 
-	return result.String(), nil
+// IsResponseTooLarge 判断错误是否因为响应超过最大读取大小
+func IsResponseTooLarge(err error) bool {
+	return errors.Is(err, errResponseTooLarge)
 }
 
-// readWithEncoding 使用不同的编码读取响应
-func (c *Client) readWithEncoding(conn net.Conn) (string, error) {
-	// 重新读取所有数据
-	// 限制最大读取大小，避免内存无限增长
-	const maxReadSize = 512 * 1024 // 512KB最大限制
+// readResponse 读取服务器的全部响应，直到服务器关闭连接
+// 出错时同时返回已经读到的数据；响应超过 maxResponseSize 时返回前 maxResponseSize 字节和 errResponseTooLarge
+func readResponse(conn net.Conn) ([]byte, error) {
 	buf := make([]byte, 0, 4096)
 	tmp := make([]byte, 4096)
 
 	for {
 		n, err := conn.Read(tmp)
 		if n > 0 {
-			// 检查是否超过最大大小限制
-			if len(buf)+n > maxReadSize {
-				// 只读取到最大限制
-				remaining := maxReadSize - len(buf)
-				if remaining > 0 {
-					buf = append(buf, tmp[:remaining]...)
-				}
-				break
+			if len(buf)+n > maxResponseSize {
+				return append(buf, tmp[:maxResponseSize-len(buf)]...), errResponseTooLarge
 			}
 			buf = append(buf, tmp[:n]...)
 		}
+		if err == io.EOF {
+			return buf, nil
+		}
 		if err != nil {
-			break
+			return buf, err
 		}
 	}
+}
 
-	// 尝试不同的编码
-	encodings := []encoding.Encoding{
-		unicode.UTF8,
-		charmap.ISO8859_1,
-		charmap.Windows1252,
-	}
-
-	for _, enc := range encodings {
-		decoder := enc.NewDecoder()
-		decoded, err := decoder.Bytes(buf)
-		if err == nil {
-			return string(decoded), nil
+// decodeResponse 将响应转换为 UTF-8 文本，并统一换行符
+// 不是合法 UTF-8 的响应依次尝试其他常见编码
+func decodeResponse(data []byte) string {
+	text := string(data)
+	if !utf8.Valid(data) {
+		encodings := []encoding.Encoding{
+			charmap.Windows1252,
+			charmap.ISO8859_1,
+		}
+		for _, enc := range encodings {
+			if decoded, err := enc.NewDecoder().Bytes(data); err == nil {
+				text = string(decoded)
+				break
+			}
 		}
 	}
 
-	// 如果所有编码都失败，返回原始字符串（忽略无效字符）
-	return string(buf), nil
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text
 }
 
 // dial 建立到 WHOIS 服务器的连接
-func (c *Client) dial(host, port string) (net.Conn, error) {
+func (c *Client) dial(ctx context.Context, host, port string) (net.Conn, error) {
 	address := net.JoinHostPort(host, port)
 
+	// 如果配置了代理池
+	if c.proxyPool != nil {
+		return c.dialWithProxyPool(ctx, host, address)
+	}
+
 	// 如果配置了代理
 	if c.proxy != nil {
-		return c.dialWithProxy(address)
+		return c.dialWithProxy(ctx, address)
 	}
 
 	// 直接连接
 	dialer := &net.Dialer{
 		Timeout: c.timeout,
 	}
-	return dialer.Dial("tcp", address)
+	return dialer.DialContext(ctx, "tcp", address)
 }
 
-// dialWithProxy 通过代理建立连接
-func (c *Client) dialWithProxy(address string) (net.Conn, error) {
-	if c.proxy == nil {
-		return nil, &ProxyError{Message: "proxy not configured"}
-	}
+// SetServerLimit 设置指定 WHOIS 服务器的限速与并发上限，覆盖内置默认值
+func (c *Client) SetServerLimit(server string, limit ServerLimit) {
+	c.limiter.setLimit(server, limit)
+}
 
-	var auth *proxy.Auth
-	if c.proxy.User != nil {
-		password, _ := c.proxy.User.Password()
-		auth = &proxy.Auth{
-			User:     c.proxy.User.Username(),
-			Password: password,
-		}
-	}
+// SetDefaultServerLimit 设置没有单独配置的 WHOIS 服务器的限速与并发上限，默认不限制
+func (c *Client) SetDefaultServerLimit(limit ServerLimit) {
+	c.limiter.setLimit("", limit)
+}
 
-	// 创建代理拨号器
-	var dialer proxy.Dialer
-	var err error
-	if c.proxy.Scheme == "socks5" {
-		dialer, err = proxy.SOCKS5("tcp", c.proxy.Host, auth, proxy.Direct)
-	} else {
-		// 对于 HTTP 代理，使用标准拨号
-		dialer = &net.Dialer{Timeout: c.timeout}
-	}
+// ProxyPool 返回当前使用的代理池，未设置时为 nil
+func (c *Client) ProxyPool() *ProxyPool {
+	return c.proxyPool
+}
 
-	if err != nil {
-		return nil, &ProxyError{Message: "failed to create proxy dialer", Err: err}
+// SetProxyPool 设置代理池，之后的连接按代理池策略轮换代理
+func (c *Client) SetProxyPool(pool *ProxyPool) {
+	c.proxyPool = pool
+}
+
+// ProxyStats 返回代理池中每个代理的使用统计，未设置代理池时返回 nil
+func (c *Client) ProxyStats() []ProxyStats {
+	if c.proxyPool == nil {
+		return nil
 	}
+	return c.proxyPool.Stats()
+}
 
-	// 通过代理连接
-	conn, err := dialer.Dial("tcp", address)
-	if err != nil {
-		return nil, &ProxyError{
-			Message: fmt.Sprintf("failed to connect via proxy %s", c.proxy.Host),
-			Err:     err,
-		}
+// dialWithProxyPool 从代理池选择代理建立连接，并记录代理的健康状态
+func (c *Client) dialWithProxyPool(ctx context.Context, server, address string) (net.Conn, error) {
+	px := c.proxyPool.acquire(server)
+	conn, err := dialViaProxy(ctx, px.url, address, c.timeout)
+
+	// 取消导致的失败与代理无关；代理正常但目标无法连接时也不改变代理的健康状态
+	if ctx.Err() == nil && !isProxyTargetError(err) {
+		c.proxyPool.report(px, err)
 	}
+	return conn, err
+}
 
-	return conn, nil
+// dialWithProxy 通过代理建立连接
+func (c *Client) dialWithProxy(ctx context.Context, address string) (net.Conn, error) {
+	if c.proxy == nil {
+		return nil, &ProxyError{Message: "proxy not configured"}
+	}
+	return dialViaProxy(ctx, c.proxy, address, c.timeout)
 }
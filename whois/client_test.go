@@ -0,0 +1,40 @@
+package whois
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestReadResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantLen int
+		wantErr error
+	}{
+		{"small", 100, 100, nil},
+		{"exactly max", maxResponseSize, maxResponseSize, nil},
+		{"too large", maxResponseSize + 5000, maxResponseSize, errResponseTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			go func() {
+				server.Write(bytes.Repeat([]byte("x"), tt.size))
+				server.Close()
+			}()
+			defer client.Close()
+
+			data, err := readResponse(client)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("readResponse error = %v, want %v", err, tt.wantErr)
+			}
+			if len(data) != tt.wantLen {
+				t.Errorf("readResponse returned %d bytes, want %d", len(data), tt.wantLen)
+			}
+		})
+	}
+}
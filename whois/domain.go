@@ -0,0 +1,117 @@
+package whois
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// NormalizeDomain 将输入标准化为可注册域名，返回 A-label（punycode）和 Unicode 两种形式
+// 例如 www.例子.中国 -> xn--fsqu00a.xn--fiqs8s, 例子.中国
+func NormalizeDomain(domain string) (string, string, error) {
+	ascii, _, err := parseDomain(domain)
+	if err != nil {
+		return "", "", err
+	}
+	return ascii, toUnicode(ascii), nil
+}
+
+// parseDomain 解析域名，返回 A-label 形式的可注册域名及其公共后缀
+// 输入按 IDNA2008/UTS-46 转换为 punycode，再基于内嵌的 Public Suffix List 解析，例如:
+// - sub.example.com -> example.com, com
+// - www.example.co.uk -> example.co.uk, co.uk
+// - münchen.de -> xn--mnchen-3ya.de, de
+func parseDomain(domain string) (string, string, error) {
+	// 去除协议
+	domain = strings.TrimPrefix(domain, "http://")
+	domain = strings.TrimPrefix(domain, "https://")
+	domain = strings.ToLower(strings.TrimSpace(domain))
+
+	// 去除路径
+	if idx := strings.Index(domain, "/"); idx != -1 {
+		domain = domain[:idx]
+	}
+
+	// 去除 FQDN 末尾的点
+	domain = strings.TrimSuffix(domain, ".")
+
+	// 转换为 A-label，同时完成 UTS-46 映射与 IDNA2008 校验
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", "", &BadDomainError{Domain: domain, Reason: err.Error()}
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", "", &BadDomainError{Domain: domain, Reason: "missing top-level domain"}
+	}
+	for _, label := range labels {
+		if label == "" {
+			return "", "", &BadDomainError{Domain: domain, Reason: "empty label"}
+		}
+	}
+
+	suffix := effectiveSuffix(ascii)
+	if suffix == ascii {
+		// 输入本身就是公共后缀，没有可注册的部分
+		return "", "", &BadDomainError{Domain: domain, Reason: "domain is a public suffix"}
+	}
+
+	// 可注册域名 = 公共后缀 + 左侧一个标签
+	prefix := strings.TrimSuffix(ascii, "."+suffix)
+	registrable := prefix[strings.LastIndex(prefix, ".")+1:] + "." + suffix
+
+	return registrable, suffix, nil
+}
+
+// NormalizeTLD 标准化 TLD 或公共后缀：去除首尾的点、转小写并转换为 A-label
+func NormalizeTLD(tld string) string {
+	return toASCII(strings.ToLower(strings.Trim(strings.TrimSpace(tld), ".")))
+}
+
+// toUnicode 将 A-label 域名转换为 Unicode 形式，转换失败时原样返回
+func toUnicode(ascii string) string {
+	unicode, err := idna.Lookup.ToUnicode(ascii)
+	if err != nil {
+		return ascii
+	}
+	return unicode
+}
+
+// toASCII 将域名或 TLD 转换为 A-label 形式，转换失败时原样返回
+func toASCII(name string) string {
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return name
+	}
+	return ascii
+}
+
+// effectiveSuffix 返回域名的 ICANN 公共后缀
+// 私有后缀（如 github.io、blogspot.com）不是注册局边界，会回退到其上层的 ICANN 后缀
+func effectiveSuffix(domain string) string {
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	for !icann {
+		idx := strings.Index(suffix, ".")
+		if idx == -1 {
+			// 未知 TLD，按最后一个标签处理
+			return suffix
+		}
+		suffix, icann = publicsuffix.PublicSuffix(suffix[idx+1:])
+	}
+	return suffix
+}
+
+// suffixCandidates 返回从长到短的后缀候选，例如 gov.uk -> [gov.uk uk]
+func suffixCandidates(suffix string) []string {
+	candidates := []string{suffix}
+	for {
+		idx := strings.Index(suffix, ".")
+		if idx == -1 {
+			return candidates
+		}
+		suffix = suffix[idx+1:]
+		candidates = append(candidates, suffix)
+	}
+}
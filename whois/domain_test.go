@@ -0,0 +1,119 @@
+package whois
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDomain(t *testing.T) {
+	tests := []struct {
+		domain      string
+		registrable string
+		suffix      string
+		wantErr     bool
+	}{
+		{domain: "example.com", registrable: "example.com", suffix: "com"},
+		{domain: "sub.example.com", registrable: "example.com", suffix: "com"},
+		{domain: "a.b.sub.example.com", registrable: "example.com", suffix: "com"},
+		{domain: "www.example.co.uk", registrable: "example.co.uk", suffix: "co.uk"},
+		{domain: "foo.gov.uk", registrable: "foo.gov.uk", suffix: "gov.uk"},
+		{domain: "www.foo.gov.uk", registrable: "foo.gov.uk", suffix: "gov.uk"},
+		// 私有后缀不是注册局边界
+		{domain: "x.github.io", registrable: "github.io", suffix: "io"},
+		{domain: "github.io", registrable: "github.io", suffix: "io"},
+		{domain: "foo.blogspot.com", registrable: "blogspot.com", suffix: "com"},
+		// 未知 TLD 按最后一个标签处理
+		{domain: "www.example.zzzz", registrable: "example.zzzz", suffix: "zzzz"},
+		{domain: "WWW.Example.COM.", registrable: "example.com", suffix: "com"},
+		{domain: "https://www.example.com/path", registrable: "example.com", suffix: "com"},
+		{domain: "münchen.de", registrable: "xn--mnchen-3ya.de", suffix: "de"},
+		{domain: "www.例子.中国", registrable: "xn--fsqu00a.xn--fiqs8s", suffix: "xn--fiqs8s"},
+		// 只有公共后缀，没有可注册的部分
+		{domain: "com", wantErr: true},
+		{domain: "co.uk", wantErr: true},
+		{domain: "gov.uk", wantErr: true},
+		{domain: "", wantErr: true},
+		{domain: "example..com", wantErr: true},
+		{domain: "exa mple.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		registrable, suffix, err := parseDomain(tt.domain)
+		if tt.wantErr {
+			var badDomain *BadDomainError
+			if !errors.As(err, &badDomain) {
+				t.Errorf("parseDomain(%q) = %q, %q, %v, want BadDomainError", tt.domain, registrable, suffix, err)
+			}
+			continue
+		}
+		if err != nil || registrable != tt.registrable || suffix != tt.suffix {
+			t.Errorf("parseDomain(%q) = %q, %q, %v, want %q, %q", tt.domain, registrable, suffix, err, tt.registrable, tt.suffix)
+		}
+	}
+}
+
+func TestEffectiveSuffix(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"sub.example.com", "com"},
+		{"www.example.co.uk", "co.uk"},
+		{"foo.gov.uk", "gov.uk"},
+		{"x.github.io", "io"},
+		{"bucket.s3.amazonaws.com", "com"},
+		{"example.zzzz", "zzzz"},
+		{"co.uk", "co.uk"},
+	}
+	for _, tt := range tests {
+		if got := effectiveSuffix(tt.domain); got != tt.want {
+			t.Errorf("effectiveSuffix(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestLookupSuffix(t *testing.T) {
+	registry, err := NewTLDRegistry()
+	if err != nil {
+		t.Fatalf("NewTLDRegistry: %v", err)
+	}
+
+	tests := []struct {
+		suffix  string
+		matched string
+		server  string
+		ok      bool
+	}{
+		{suffix: "com", matched: "com", server: "whois.verisign-grs.com", ok: true},
+		// gov.uk 有自己的服务器，co.uk 回退到 uk
+		{suffix: "gov.uk", matched: "gov.uk", server: "whois.ja.net", ok: true},
+		{suffix: "co.uk", matched: "uk", server: "whois.nic.uk", ok: true},
+		{suffix: "io", matched: "io", server: "whois.nic.io", ok: true},
+		{suffix: "中国", matched: "xn--fiqs8s", server: "cwhois.cnnic.cn", ok: true},
+		{suffix: "xn--fiqs8s", matched: "xn--fiqs8s", server: "cwhois.cnnic.cn", ok: true},
+		{suffix: "zzzz", ok: false},
+		{suffix: "foo.zzzz", ok: false},
+	}
+	for _, tt := range tests {
+		matched, server, ok := registry.LookupSuffix(tt.suffix)
+		if matched != tt.matched || server != tt.server || ok != tt.ok {
+			t.Errorf("LookupSuffix(%q) = %q, %q, %v, want %q, %q, %v", tt.suffix, matched, server, ok, tt.matched, tt.server, tt.ok)
+		}
+	}
+
+	// 解析结果与查找配合：子域名按其公共后缀路由
+	for domain, want := range map[string]string{
+		"foo.gov.uk":        "whois.ja.net",
+		"www.example.co.uk": "whois.nic.uk",
+		"x.github.io":       "whois.nic.io",
+		"sub.example.com":   "whois.verisign-grs.com",
+	} {
+		_, suffix, err := parseDomain(domain)
+		if err != nil {
+			t.Fatalf("parseDomain(%q): %v", domain, err)
+		}
+		if _, server, _ := registry.LookupSuffix(suffix); server != want {
+			t.Errorf("%s routed to %q, want %q", domain, server, want)
+		}
+	}
+}
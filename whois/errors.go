@@ -1,6 +1,9 @@
 package whois
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // WhoisError 是所有 WHOIS 相关错误的基础类型
 type WhoisError struct {
@@ -30,9 +33,13 @@ func NewWhoisError(message string, err error) *WhoisError {
 // BadDomainError 无效域名错误
 type BadDomainError struct {
 	Domain string
+	Reason string
 }
 
 func (e *BadDomainError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("invalid domain: %s (%s)", e.Domain, e.Reason)
+	}
 	return fmt.Sprintf("invalid domain: %s", e.Domain)
 }
 
@@ -45,14 +52,89 @@ func (e *NoWhoisServerFoundError) Error() string {
 	return fmt.Sprintf("no whois server found for TLD: %s", e.TLD)
 }
 
-// SocketTimeoutError 连接超时错误
+// 超时发生的阶段
+const (
+	PhaseConnect = "connect"
+	PhaseWrite   = "write"
+	PhaseRead    = "read"
+)
+
+// SocketTimeoutError 连接或读写超时错误
 type SocketTimeoutError struct {
 	Server string
 	Query  string
+	Phase  string // PhaseConnect、PhaseWrite 或 PhaseRead
+	Err    error
 }
 
 func (e *SocketTimeoutError) Error() string {
-	return fmt.Sprintf("timeout querying %s for %s", e.Server, e.Query)
+	return fmt.Sprintf("%s timeout querying %s for %s", e.Phase, e.Server, e.Query)
+}
+
+func (e *SocketTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout 实现 net.Error 的超时判断
+func (e *SocketTimeoutError) Timeout() bool {
+	return true
+}
+
+// ConnectionRefusedError WHOIS 服务器拒绝连接
+type ConnectionRefusedError struct {
+	Server string
+	Query  string
+	Err    error
+}
+
+func (e *ConnectionRefusedError) Error() string {
+	return fmt.Sprintf("connection refused by %s for %s", e.Server, e.Query)
+}
+
+func (e *ConnectionRefusedError) Unwrap() error {
+	return e.Err
+}
+
+// DNSError 无法解析 WHOIS 服务器地址
+type DNSError struct {
+	Server string
+	Query  string
+	Err    error
+}
+
+func (e *DNSError) Error() string {
+	return fmt.Sprintf("failed to resolve %s for %s: %v", e.Server, e.Query, e.Err)
+}
+
+func (e *DNSError) Unwrap() error {
+	return e.Err
+}
+
+// TruncatedResponseError 读取响应的过程中连接中断或响应超过最大大小，只收到了部分数据
+type TruncatedResponseError struct {
+	Server   string
+	Query    string
+	Received int // 中断前或达到大小上限时收到的字节数
+	Err      error
+}
+
+func (e *TruncatedResponseError) Error() string {
+	return fmt.Sprintf("truncated response from %s for %s after %d bytes: %v", e.Server, e.Query, e.Received, e.Err)
+}
+
+func (e *TruncatedResponseError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitedError 服务器返回限速、配额用尽或拒绝访问的响应
+type RateLimitedError struct {
+	Server     string
+	Query      string
+	RetryAfter time.Duration // 服务器建议的等待时间，未知时为 0
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited by %s for %s", e.Server, e.Query)
 }
 
 // SocketError 连接错误
@@ -100,3 +182,30 @@ func (e *ProxyError) Error() string {
 func (e *ProxyError) Unwrap() error {
 	return e.Err
 }
+
+// NoRDAPServerFoundError RDAP 服务未找到错误
+type NoRDAPServerFoundError struct {
+	TLD string
+}
+
+func (e *NoRDAPServerFoundError) Error() string {
+	return fmt.Sprintf("no rdap server found for TLD: %s", e.TLD)
+}
+
+// RDAPError RDAP 请求错误
+type RDAPError struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *RDAPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("rdap request to %s failed: %v", e.URL, e.Err)
+	}
+	return fmt.Sprintf("rdap request to %s failed: unexpected status %d", e.URL, e.StatusCode)
+}
+
+func (e *RDAPError) Unwrap() error {
+	return e.Err
+}
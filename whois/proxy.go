@@ -0,0 +1,246 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ValidateProxyURL 检查代理地址是否受支持
+// 支持 http、https（CONNECT 隧道）、socks5（本地解析域名）和 socks5h（代理端解析域名）
+func ValidateProxyURL(proxyURL *url.URL) error {
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return &ProxyError{Message: fmt.Sprintf("unsupported proxy scheme %q (supported: http, https, socks5, socks5h)", proxyURL.Scheme)}
+	}
+	if proxyURL.Host == "" {
+		return &ProxyError{Message: fmt.Sprintf("missing proxy host in %s", proxyURL.Redacted())}
+	}
+	return nil
+}
+
+// ProxyFromEnvironment 从 ALL_PROXY、HTTPS_PROXY 环境变量（含小写形式）读取代理配置
+// 未设置时返回 nil
+func ProxyFromEnvironment() (*url.URL, error) {
+	for _, key := range []string{"ALL_PROXY", "all_proxy", "HTTPS_PROXY", "https_proxy"} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+
+		proxyURL, err := url.Parse(value)
+		if err != nil {
+			return nil, &ProxyError{Message: fmt.Sprintf("invalid proxy in %s", key), Err: err}
+		}
+		if err := ValidateProxyURL(proxyURL); err != nil {
+			return nil, err
+		}
+		return proxyURL, nil
+	}
+	return nil, nil
+}
+
+// dialViaProxy 通过指定代理连接目标地址
+func dialViaProxy(ctx context.Context, proxyURL *url.URL, address string, timeout time.Duration) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		conn, err = dialSOCKS5(ctx, proxyURL, address, timeout)
+	case "http", "https":
+		conn, err = dialHTTPConnect(ctx, proxyURL, address, timeout)
+	default:
+		return nil, ValidateProxyURL(proxyURL)
+	}
+
+	if err != nil {
+		return nil, &ProxyError{
+			Message: fmt.Sprintf("failed to connect via proxy %s", proxyURL.Host),
+			Err:     err,
+		}
+	}
+	return conn, nil
+}
+
+// proxyTargetError 代理本身工作正常，但无法连接目标服务器，例如目标拒绝连接或域名无法解析
+// 这类失败换一个代理也不会好转，不应让代理进入冷却期
+type proxyTargetError struct {
+	err error
+}
+
+func (e *proxyTargetError) Error() string {
+	return e.err.Error()
+}
+
+func (e *proxyTargetError) Unwrap() error {
+	return e.err
+}
+
+// isProxyTargetError 判断经代理连接的错误是否是目标服务器无法连接，而不是代理本身的问题
+func isProxyTargetError(err error) bool {
+	var targetErr *proxyTargetError
+	return errors.As(err, &targetErr)
+}
+
+// socksTargetReplies 表示代理无法连接目标的 SOCKS5 应答，x/net/proxy 以 "unknown error <应答>" 的形式返回
+var socksTargetReplies = map[string]bool{
+	"unknown error general SOCKS server failure": true,
+	"unknown error network unreachable":          true,
+	"unknown error host unreachable":             true,
+	"unknown error connection refused":           true,
+	"unknown error TTL expired":                  true,
+}
+
+// dialSOCKS5 通过 SOCKS5 代理连接
+// socks5 在本地解析目标域名，socks5h 将域名交给代理解析
+func dialSOCKS5(ctx context.Context, proxyURL *url.URL, address string, timeout time.Duration) (net.Conn, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{
+			User:     proxyURL.User.Username(),
+			Password: password,
+		}
+	}
+
+	forward := &net.Dialer{Timeout: timeout}
+	dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL.Scheme == "socks5" {
+		address, err = resolveAddress(ctx, address)
+		if err != nil {
+			return nil, &proxyTargetError{err: err}
+		}
+	}
+
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	if opErr, ok := err.(*net.OpError); ok && opErr.Err != nil && socksTargetReplies[opErr.Err.Error()] {
+		return nil, &proxyTargetError{err: err}
+	}
+	return conn, err
+}
+
+// resolveAddress 在本地将 host:port 中的域名解析为 IP
+func resolveAddress(ctx context.Context, address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return address, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no addresses found for %s", host)
+	}
+	return net.JoinHostPort(ips[0].IP.String(), port), nil
+}
+
+// dialHTTPConnect 通过 HTTP/HTTPS 代理的 CONNECT 方法建立隧道
+func dialHTTPConnect(ctx context.Context, proxyURL *url.URL, address string, timeout time.Duration) (net.Conn, error) {
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// 握手阶段受超时和 ctx 约束
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := proxyURL.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		err := fmt.Errorf("proxy CONNECT to %s failed: %s", address, resp.Status)
+		// 502/504 表示代理无法连接或等待目标超时，其余状态（如 407 认证失败）是代理的问题
+		if resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout {
+			return nil, &proxyTargetError{err: err}
+		}
+		return nil, err
+	}
+
+	// 清除握手超时，由调用方重新设置
+	_ = conn.SetDeadline(time.Time{})
+
+	// 代理可能在响应后紧接着发送了数据，保留已缓冲的部分
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读取缓冲区中剩余数据的连接
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
@@ -0,0 +1,185 @@
+package whois
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 代理轮换策略
+const (
+	ProxyStrategyRoundRobin = "round-robin"
+	ProxyStrategyLRU        = "lru"
+)
+
+// ProxyStats 单个代理的使用统计
+type ProxyStats struct {
+	Proxy     string `json:"proxy"`
+	Success   int64  `json:"success"`
+	Failed    int64  `json:"failed"`
+	Unhealthy bool   `json:"unhealthy"`
+}
+
+// ProxyPool 代理池，按策略轮换代理，连接失败的代理在冷却期内不再使用
+type ProxyPool struct {
+	mu       sync.Mutex
+	proxies  []*poolProxy
+	strategy string
+	cooldown time.Duration
+	next     int
+	// lastUsed 记录每个 WHOIS 服务器上各代理最近一次使用的时间，用于 lru 策略
+	lastUsed map[string][]time.Time
+}
+
+// poolProxy 代理池中的一个代理
+type poolProxy struct {
+	url            *url.URL
+	unhealthyUntil time.Time
+	success        int64
+	failed         int64
+}
+
+// NewProxyPool 创建一个新的代理池
+func NewProxyPool(proxyURLs []*url.URL, strategy string, cooldown time.Duration) (*ProxyPool, error) {
+	if len(proxyURLs) == 0 {
+		return nil, &ProxyError{Message: "proxy pool is empty"}
+	}
+
+	switch strategy {
+	case "":
+		strategy = ProxyStrategyRoundRobin
+	case ProxyStrategyRoundRobin, ProxyStrategyLRU:
+	default:
+		return nil, &ProxyError{Message: fmt.Sprintf("unsupported proxy strategy %q (supported: %s, %s)", strategy, ProxyStrategyRoundRobin, ProxyStrategyLRU)}
+	}
+
+	pool := &ProxyPool{
+		strategy: strategy,
+		cooldown: cooldown,
+		lastUsed: make(map[string][]time.Time),
+	}
+	for _, proxyURL := range proxyURLs {
+		if err := ValidateProxyURL(proxyURL); err != nil {
+			return nil, err
+		}
+		pool.proxies = append(pool.proxies, &poolProxy{url: proxyURL})
+	}
+
+	return pool, nil
+}
+
+// LoadProxyFile 从文件读取代理列表，每行一个代理地址，支持 # 注释和空行
+func LoadProxyFile(path string) ([]*url.URL, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ProxyError{Message: fmt.Sprintf("failed to read proxy file %s", path), Err: err}
+	}
+
+	var proxyURLs []*url.URL
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		proxyURL, err := url.Parse(line)
+		if err != nil {
+			return nil, &ProxyError{Message: fmt.Sprintf("invalid proxy at %s:%d", path, i+1), Err: err}
+		}
+		if err := ValidateProxyURL(proxyURL); err != nil {
+			return nil, &ProxyError{Message: fmt.Sprintf("invalid proxy at %s:%d", path, i+1), Err: err}
+		}
+		proxyURLs = append(proxyURLs, proxyURL)
+	}
+
+	if len(proxyURLs) == 0 {
+		return nil, &ProxyError{Message: fmt.Sprintf("no proxies found in %s", path)}
+	}
+	return proxyURLs, nil
+}
+
+// acquire 为指定 WHOIS 服务器选择一个代理
+// 优先选择健康的代理；全部处于冷却期时选择最早恢复的一个
+func (p *ProxyPool) acquire(server string) *poolProxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	chosen := -1
+
+	switch p.strategy {
+	case ProxyStrategyLRU:
+		lastUsed := p.lastUsed[server]
+		if lastUsed == nil {
+			lastUsed = make([]time.Time, len(p.proxies))
+			p.lastUsed[server] = lastUsed
+		}
+		for i, px := range p.proxies {
+			if px.unhealthyUntil.After(now) {
+				continue
+			}
+			if chosen == -1 || lastUsed[i].Before(lastUsed[chosen]) {
+				chosen = i
+			}
+		}
+	default:
+		for n := 0; n < len(p.proxies); n++ {
+			i := (p.next + n) % len(p.proxies)
+			if !p.proxies[i].unhealthyUntil.After(now) {
+				chosen = i
+				p.next = i + 1
+				break
+			}
+		}
+	}
+
+	if chosen == -1 {
+		chosen = 0
+		for i, px := range p.proxies {
+			if px.unhealthyUntil.Before(p.proxies[chosen].unhealthyUntil) {
+				chosen = i
+			}
+		}
+	}
+
+	// 冷却期内被选中的代理同样记录使用时间，恢复后按实际使用顺序轮换
+	if p.strategy == ProxyStrategyLRU {
+		p.lastUsed[server][chosen] = now
+	}
+	return p.proxies[chosen]
+}
+
+// report 记录代理的使用结果，失败的代理进入冷却期
+func (p *ProxyPool) report(px *poolProxy, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		px.failed++
+		px.unhealthyUntil = time.Now().Add(p.cooldown)
+		return
+	}
+	px.success++
+	px.unhealthyUntil = time.Time{}
+}
+
+// Stats 返回每个代理的使用统计
+func (p *ProxyPool) Stats() []ProxyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]ProxyStats, 0, len(p.proxies))
+	for _, px := range p.proxies {
+		stats = append(stats, ProxyStats{
+			Proxy:     px.url.Redacted(),
+			Success:   px.success,
+			Failed:    px.failed,
+			Unhealthy: px.unhealthyUntil.After(now),
+		})
+	}
+	return stats
+}
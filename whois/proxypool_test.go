@@ -0,0 +1,176 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestProxyPoolLRUFallback(t *testing.T) {
+	proxies := []*url.URL{{Scheme: "http", Host: "127.0.0.1:1"}, {Scheme: "http", Host: "127.0.0.1:2"}}
+	pool, err := NewProxyPool(proxies, ProxyStrategyLRU, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const server = "whois.example"
+	pool.report(pool.proxies[0], errors.New("down"))
+	pool.report(pool.proxies[1], errors.New("down"))
+
+	// 全部在冷却期时选择最早恢复的代理，并记录这次使用
+	if px := pool.acquire(server); px != pool.proxies[0] {
+		t.Fatalf("fallback chose %s, want the proxy that recovers first", px.url.Host)
+	}
+
+	pool.report(pool.proxies[0], nil)
+	pool.report(pool.proxies[1], nil)
+	if px := pool.acquire(server); px != pool.proxies[1] {
+		t.Errorf("after recovery chose %s, want the proxy not used during the fallback", px.url.Host)
+	}
+}
+
+func TestDialWithProxyPoolHealth(t *testing.T) {
+	tests := []struct {
+		name          string
+		scheme        string
+		serve         func(conn net.Conn)
+		wantUnhealthy bool
+	}{
+		{
+			name:   "http target unreachable",
+			scheme: "http",
+			serve:  httpProxyReply("502 Bad Gateway"),
+		},
+		{
+			name:   "http gateway timeout",
+			scheme: "http",
+			serve:  httpProxyReply("504 Gateway Timeout"),
+		},
+		{
+			name:          "http auth required",
+			scheme:        "http",
+			serve:         httpProxyReply("407 Proxy Authentication Required"),
+			wantUnhealthy: true,
+		},
+		{
+			name:          "http handshake closed",
+			scheme:        "http",
+			serve:         func(conn net.Conn) { conn.Close() },
+			wantUnhealthy: true,
+		},
+		{
+			name:   "socks host unreachable",
+			scheme: "socks5h",
+			serve:  socksProxyReply(0x04),
+		},
+		{
+			name:   "socks connection refused",
+			scheme: "socks5h",
+			serve:  socksProxyReply(0x05),
+		},
+		{
+			name:          "socks not allowed",
+			scheme:        "socks5h",
+			serve:         socksProxyReply(0x02),
+			wantUnhealthy: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				tt.serve(conn)
+			}()
+
+			stats := dialThroughPool(t, &url.URL{Scheme: tt.scheme, Host: ln.Addr().String()})
+			if stats.Unhealthy != tt.wantUnhealthy || stats.Failed > 0 != tt.wantUnhealthy {
+				t.Errorf("proxy stats = %+v, want unhealthy %v", stats, tt.wantUnhealthy)
+			}
+		})
+	}
+
+	t.Run("proxy refuses connection", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := ln.Addr().String()
+		ln.Close()
+
+		if stats := dialThroughPool(t, &url.URL{Scheme: "http", Host: address}); !stats.Unhealthy {
+			t.Errorf("proxy stats = %+v, want unhealthy", stats)
+		}
+	})
+}
+
+// dialThroughPool 通过只有一个代理的代理池连接，返回连接后的代理统计
+func dialThroughPool(t *testing.T, proxyURL *url.URL) ProxyStats {
+	t.Helper()
+	client, err := NewClient(5*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := NewProxyPool([]*url.URL{proxyURL}, ProxyStrategyRoundRobin, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetProxyPool(pool)
+
+	conn, err := client.dialWithProxyPool(context.Background(), "whois.example", "whois.example:43")
+	if err == nil {
+		conn.Close()
+		t.Fatal("dial succeeded, want error")
+	}
+	return client.ProxyStats()[0]
+}
+
+// httpProxyReply 读取 CONNECT 请求后返回指定状态的 HTTP 代理
+func httpProxyReply(status string) func(net.Conn) {
+	return func(conn net.Conn) {
+		if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 "+status+"\r\nContent-Length: 0\r\n\r\n")
+	}
+}
+
+// socksProxyReply 接受无认证握手后以指定应答码拒绝 CONNECT 请求的 SOCKS5 代理
+func socksProxyReply(reply byte) func(net.Conn) {
+	return func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(reader, greeting); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(reader, make([]byte, greeting[1])); err != nil {
+			return
+		}
+		conn.Write([]byte{5, 0})
+
+		// 请求: VER CMD RSV ATYP=域名 LEN 域名 PORT
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(reader, make([]byte, int(header[4])+2)); err != nil {
+			return
+		}
+		conn.Write([]byte{5, reply, 0, 1, 0, 0, 0, 0, 0, 0})
+	}
+}
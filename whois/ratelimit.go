@@ -0,0 +1,194 @@
+package whois
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerLimit 单个 WHOIS 服务器的访问限制
+type ServerLimit struct {
+	Rate        float64 // 每秒允许的查询数，0 表示不限速
+	Burst       int     // 令牌桶容量，允许的突发查询数
+	MaxInFlight int     // 同时进行的最大查询数，0 表示不限制
+}
+
+// DefaultServerLimits 已知限制严格的 WHOIS 服务器的内置限制
+var DefaultServerLimits = map[string]ServerLimit{
+	"whois.iana.org":         {Rate: 2, Burst: 5, MaxInFlight: 4},
+	"whois.verisign-grs.com": {Rate: 5, Burst: 10, MaxInFlight: 10},
+	"whois.denic.de":         {Rate: 0.5, Burst: 2, MaxInFlight: 2},
+	"whois.nic.uk":           {Rate: 1, Burst: 2, MaxInFlight: 2},
+	"whois.jprs.jp":          {Rate: 1, Burst: 1, MaxInFlight: 1},
+	"whois.cnnic.cn":         {Rate: 1, Burst: 2, MaxInFlight: 2}, // .cn
+	"cwhois.cnnic.cn":        {Rate: 1, Burst: 2, MaxInFlight: 2}, // .中国、.中國
+	"whois.eu":               {Rate: 0.5, Burst: 2, MaxInFlight: 2},
+	"whois.nic.it":           {Rate: 1, Burst: 2, MaxInFlight: 2},
+	"whois.dns.pl":           {Rate: 1, Burst: 2, MaxInFlight: 2},
+}
+
+// ParseServerLimit 解析 "rate[,burst[,inflight]]" 格式的限制，例如 "0.5,2,2"
+func ParseServerLimit(value string) (ServerLimit, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) > 3 {
+		return ServerLimit{}, fmt.Errorf("invalid server limit %q: expected rate[,burst[,inflight]]", value)
+	}
+
+	var limit ServerLimit
+	var err error
+	if limit.Rate, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil || limit.Rate < 0 {
+		return ServerLimit{}, fmt.Errorf("invalid rate in server limit %q", value)
+	}
+	if len(parts) > 1 {
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil || limit.Burst < 0 {
+			return ServerLimit{}, fmt.Errorf("invalid burst in server limit %q", value)
+		}
+	}
+	if len(parts) > 2 {
+		if limit.MaxInFlight, err = strconv.Atoi(strings.TrimSpace(parts[2])); err != nil || limit.MaxInFlight < 0 {
+			return ServerLimit{}, fmt.Errorf("invalid inflight in server limit %q", value)
+		}
+	}
+
+	return limit, nil
+}
+
+// rateLimiter 按 WHOIS 服务器分别限速并限制并发
+type rateLimiter struct {
+	mu           sync.Mutex
+	limits       map[string]ServerLimit
+	defaultLimit ServerLimit
+	servers      map[string]*serverLimiter
+}
+
+// serverLimiter 单个服务器的令牌桶和并发槽位
+type serverLimiter struct {
+	bucket *tokenBucket
+	slots  chan struct{}
+}
+
+func newRateLimiter() *rateLimiter {
+	limits := make(map[string]ServerLimit, len(DefaultServerLimits))
+	for server, limit := range DefaultServerLimits {
+		limits[server] = limit
+	}
+	return &rateLimiter{
+		limits:  limits,
+		servers: make(map[string]*serverLimiter),
+	}
+}
+
+// setLimit 设置指定服务器的限制，server 为空时设置默认限制
+func (l *rateLimiter) setLimit(server string, limit ServerLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if server == "" {
+		l.defaultLimit = limit
+	} else {
+		l.limits[strings.ToLower(server)] = limit
+	}
+	// 限制变化后重新创建
+	l.servers = make(map[string]*serverLimiter)
+}
+
+// acquire 等待指定服务器的并发槽位和令牌，返回释放槽位的函数
+func (l *rateLimiter) acquire(ctx context.Context, server string) (func(), error) {
+	sl := l.serverLimiter(strings.ToLower(server))
+
+	release := func() {}
+	if sl.slots != nil {
+		select {
+		case sl.slots <- struct{}{}:
+			release = func() { <-sl.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if sl.bucket != nil {
+		if err := sl.bucket.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+func (l *rateLimiter) serverLimiter(server string) *serverLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if sl, ok := l.servers[server]; ok {
+		return sl
+	}
+
+	limit, ok := l.limits[server]
+	if !ok {
+		limit = l.defaultLimit
+	}
+
+	sl := &serverLimiter{}
+	if limit.Rate > 0 {
+		sl.bucket = newTokenBucket(limit.Rate, limit.Burst)
+	}
+	if limit.MaxInFlight > 0 {
+		sl.slots = make(chan struct{}, limit.MaxInFlight)
+	}
+	l.servers[server] = sl
+	return sl
+}
+
+// tokenBucket 令牌桶限速器
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait 阻塞直到取得一个令牌或 ctx 被取消
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package whois
+
+import "testing"
+
+func TestDefaultServerLimitsMatchTLDs(t *testing.T) {
+	tlds, err := EmbeddedTLDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := make(map[string]bool)
+	for _, server := range tlds {
+		servers[server] = true
+	}
+
+	// 限制按服务器主机名匹配，键必须与 tlds.json 中的服务器完全一致
+	for server := range DefaultServerLimits {
+		if server != "whois.iana.org" && !servers[server] {
+			t.Errorf("DefaultServerLimits has %s, which no TLD in tlds.json uses", server)
+		}
+	}
+	for _, tld := range []string{"cn", "xn--fiqs8s", "xn--fiqz9s"} {
+		if _, ok := DefaultServerLimits[tlds[tld]]; !ok {
+			t.Errorf("no default limit for %s server %s", tld, tlds[tld])
+		}
+	}
+}
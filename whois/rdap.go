@@ -0,0 +1,378 @@
+package whois
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RDAP 响应最大读取大小
+const maxRDAPResponseSize = 2 * 1024 * 1024
+
+// RDAPClient RDAP 客户端
+type RDAPClient struct {
+	httpClient *http.Client
+	timeout    time.Duration
+	mu         sync.RWMutex
+	bootstrap  *RDAPBootstrap
+	// limiter 按 RDAP 服务器主机名限速并限制并发
+	limiter *rateLimiter
+	// autoRefresh 内嵌引导数据中找不到 TLD 时从 IANA 下载一次完整数据
+	autoRefresh sync.Once
+}
+
+// NewRDAPClient 创建一个新的 RDAP 客户端
+func NewRDAPClient(timeout time.Duration, proxyURL *url.URL) (*RDAPClient, error) {
+	bootstrap, err := LoadRDAPBootstrap()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RDAPClient{
+		httpClient: NewHTTPClient(timeout, proxyURL),
+		timeout:    timeout,
+		bootstrap:  bootstrap,
+		limiter:    newRateLimiter(),
+	}, nil
+}
+
+// SetProxyPool 设置代理池，每个 RDAP 请求从代理池中选择代理，失败的代理进入冷却期
+// 为了在代理之间轮换，设置代理池后不再复用连接
+func (c *RDAPClient) SetProxyPool(pool *ProxyPool) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DisableKeepAlives = true
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		px := pool.acquire(host)
+		conn, err := dialViaProxy(ctx, px.url, address, c.timeout)
+
+		// 取消导致的失败与代理无关
+		if ctx.Err() == nil {
+			pool.report(px, err)
+		}
+		return conn, err
+	}
+
+	c.httpClient = &http.Client{
+		Timeout:   c.timeout,
+		Transport: transport,
+	}
+}
+
+// ShareLimits 与 WHOIS 客户端共享服务器限速配置，--server-limit 对 RDAP 服务器（按主机名）同样生效
+func (c *RDAPClient) ShareLimits(client *Client) {
+	c.limiter = client.limiter
+}
+
+// NewHTTPClient 创建用于 RDAP 和 IANA 数据下载的 HTTP 客户端
+// proxyURL 支持 http、https 和 socks5 代理
+func NewHTTPClient(timeout time.Duration, proxyURL *url.URL) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// SetBootstrap 替换当前使用的 RDAP 引导数据
+func (c *RDAPClient) SetBootstrap(bootstrap *RDAPBootstrap) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bootstrap = bootstrap
+}
+
+// RefreshBootstrap 从 IANA 重新下载引导数据
+func (c *RDAPClient) RefreshBootstrap(ctx context.Context) error {
+	bootstrap, err := DownloadRDAPBootstrap(ctx, c.httpClient)
+	if err != nil {
+		return err
+	}
+	c.SetBootstrap(bootstrap)
+	return nil
+}
+
+// rdapDomain RDAP 域名对象（RFC 9083）
+type rdapDomain struct {
+	LDHName     string           `json:"ldhName"`
+	Status      []string         `json:"status"`
+	Events      []rdapEvent      `json:"events"`
+	Nameservers []rdapNameserver `json:"nameservers"`
+	Entities    []rdapEntity     `json:"entities"`
+	Links       []rdapLink       `json:"links"`
+}
+
+type rdapEvent struct {
+	EventAction string `json:"eventAction"`
+	EventDate   string `json:"eventDate"`
+}
+
+type rdapNameserver struct {
+	LDHName string `json:"ldhName"`
+}
+
+type rdapEntity struct {
+	Roles      []string          `json:"roles"`
+	VCardArray []json.RawMessage `json:"vcardArray"`
+}
+
+type rdapLink struct {
+	Rel  string `json:"rel"`
+	Href string `json:"href"`
+	Type string `json:"type"`
+}
+
+// Fetch 通过 RDAP 查询域名信息
+// 注册局返回 404 时视为域名可用；注册商链接查询失败时忽略
+func (c *RDAPClient) Fetch(ctx context.Context, domain string) (*QueryResult, error) {
+	normalizedDomain, suffix, err := parseDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL, err := c.findRDAPServer(suffix)
+	var noServer *NoRDAPServerFoundError
+	if errors.As(err, &noServer) {
+		c.refreshEmbeddedBootstrap(ctx)
+		baseURL, err = c.findRDAPServer(suffix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	registryURL := strings.TrimSuffix(baseURL, "/") + "/domain/" + url.PathEscape(normalizedDomain)
+	registryBody, statusCode, retryAfter, err := c.get(ctx, registryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryResult{
+		Domain:         normalizedDomain,
+		UnicodeDomain:  toUnicode(normalizedDomain),
+		RegistryResult: registryBody,
+		RegistryServer: registryURL,
+		Protocol:       ProtocolRDAP,
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		result.Info = &DomainInfo{Status: StatusAvailable}
+		return result, nil
+	case http.StatusTooManyRequests:
+		return nil, &RateLimitedError{Server: registryURL, Query: normalizedDomain, RetryAfter: retryAfter}
+	default:
+		return nil, &RDAPError{URL: registryURL, StatusCode: statusCode}
+	}
+
+	var registry rdapDomain
+	if err := json.Unmarshal([]byte(registryBody), &registry); err != nil {
+		return nil, &RDAPError{URL: registryURL, Err: err}
+	}
+
+	// 跟随注册商链接，注册商数据通常更完整
+	var registrar *rdapDomain
+	if registrarURL := registry.registrarLink(registryURL); registrarURL != "" {
+		body, code, _, err := c.get(ctx, registrarURL)
+		if err == nil && code == http.StatusOK {
+			var parsed rdapDomain
+			if json.Unmarshal([]byte(body), &parsed) == nil {
+				result.RegistrarResult = body
+				result.RegistrarServer = registrarURL
+				registrar = &parsed
+			}
+		}
+	}
+
+	// 注册商查询失败时忽略错误，但取消必须向上传递
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Info = registry.domainInfo(registrar)
+	return result, nil
+}
+
+// refreshEmbeddedBootstrap 当前使用内嵌引导数据时从 IANA 下载完整数据，每个客户端最多尝试一次
+// 内嵌数据不一定包含所有 TLD，下载成功后写入本地缓存，之后不需要手动使用 --rdap-refresh
+func (c *RDAPClient) refreshEmbeddedBootstrap(ctx context.Context) {
+	c.autoRefresh.Do(func() {
+		c.mu.RLock()
+		embedded := c.bootstrap.embedded
+		c.mu.RUnlock()
+		if embedded {
+			// 下载结果供所有查询使用，不随发起查询的 ctx 取消
+			_ = c.RefreshBootstrap(context.WithoutCancel(ctx))
+		}
+	})
+}
+
+// findRDAPServer 查找公共后缀对应的 RDAP 服务地址，从最长后缀开始尝试，优先使用 https
+func (c *RDAPClient) findRDAPServer(suffix string) (string, error) {
+	var servers []string
+	c.mu.RLock()
+	for _, candidate := range suffixCandidates(suffix) {
+		if found, ok := c.bootstrap.GetServers(candidate); ok && len(found) > 0 {
+			servers = found
+			break
+		}
+	}
+	c.mu.RUnlock()
+
+	if len(servers) == 0 {
+		return "", &NoRDAPServerFoundError{TLD: suffix}
+	}
+
+	for _, server := range servers {
+		if strings.HasPrefix(server, "https://") {
+			return server, nil
+		}
+	}
+	return servers[0], nil
+}
+
+// get 发送 RDAP 请求，返回格式化后的响应正文、状态码和 Retry-After 等待时间
+func (c *RDAPClient) get(ctx context.Context, rawURL string) (string, int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", 0, 0, &RDAPError{URL: rawURL, Err: err}
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	// 等待服务器的限速令牌和并发槽位
+	release, err := c.limiter.acquire(ctx, req.URL.Hostname())
+	if err != nil {
+		return "", 0, 0, &RDAPError{URL: rawURL, Err: err}
+	}
+	defer release()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", 0, 0, &RDAPError{URL: rawURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRDAPResponseSize))
+	if err != nil {
+		return "", 0, 0, &RDAPError{URL: rawURL, Err: err}
+	}
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+
+	// 格式化 JSON，方便 normal 模式直接输出
+	var buf bytes.Buffer
+	if json.Indent(&buf, data, "", "  ") == nil {
+		return buf.String(), resp.StatusCode, retryAfter, nil
+	}
+	return string(data), resp.StatusCode, retryAfter, nil
+}
+
+// registrarLink 提取指向注册商 RDAP 服务的链接
+func (d *rdapDomain) registrarLink(selfURL string) string {
+	for _, link := range d.Links {
+		if link.Rel != "related" || link.Href == "" || link.Href == selfURL {
+			continue
+		}
+		if link.Type == "application/rdap+json" || strings.Contains(link.Href, "/domain/") {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// domainInfo 将注册局与注册商数据合并为 DomainInfo，注册商数据优先
+func (d *rdapDomain) domainInfo(registrar *rdapDomain) *DomainInfo {
+	sources := []*rdapDomain{d}
+	if registrar != nil {
+		sources = []*rdapDomain{registrar, d}
+	}
+
+	info := &DomainInfo{Status: StatusRegistered}
+	for _, src := range sources {
+		if info.Registrar == "" {
+			info.Registrar = src.registrarName()
+		}
+		if info.CreationDate == "" {
+			info.CreationDate = src.eventDate("registration")
+		}
+		if info.ExpirationDate == "" {
+			info.ExpirationDate = src.eventDate("expiration")
+		}
+		if len(info.NameServers) == 0 {
+			for _, ns := range src.Nameservers {
+				if ns.LDHName != "" {
+					info.NameServers = append(info.NameServers, strings.ToLower(ns.LDHName))
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// eventDate 提取指定事件的日期
+func (d *rdapDomain) eventDate(action string) string {
+	for _, event := range d.Events {
+		if event.EventAction == action {
+			return event.EventDate
+		}
+	}
+	return ""
+}
+
+// registrarName 从 registrar 角色实体的 vCard 中提取名称
+func (d *rdapDomain) registrarName() string {
+	for _, entity := range d.Entities {
+		for _, role := range entity.Roles {
+			if role == "registrar" {
+				return entity.vcardFN()
+			}
+		}
+	}
+	return ""
+}
+
+// vcardFN 提取 jCard 中的 fn 字段，格式: ["vcard", [["fn", {}, "text", "Name"], ...]]
+func (e *rdapEntity) vcardFN() string {
+	if len(e.VCardArray) < 2 {
+		return ""
+	}
+
+	var properties [][]json.RawMessage
+	if err := json.Unmarshal(e.VCardArray[1], &properties); err != nil {
+		return ""
+	}
+
+	for _, property := range properties {
+		if len(property) < 4 {
+			continue
+		}
+		var name, value string
+		if json.Unmarshal(property[0], &name) != nil || name != "fn" {
+			continue
+		}
+		if json.Unmarshal(property[3], &value) == nil {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
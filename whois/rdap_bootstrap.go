@@ -0,0 +1,140 @@
+package whois
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IANA 发布的 RDAP DNS 引导文件地址
+const ianaRDAPBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// rdap_dns.json 应为 IANA 引导文件的完整副本，用 go generate 下载，与 tlds.json 一样随二进制发布
+// 仓库中的版本只是部分条目的占位文件（publication 为空），发布前必须重新生成，build.sh 会检查
+//
+//go:generate curl -sSfL -o rdap_dns.json https://data.iana.org/rdap/dns.json
+//go:embed rdap_dns.json
+var rdapBootstrapData []byte
+
+// RDAPBootstrap 管理 TLD 到 RDAP 服务地址的映射（RFC 9224）
+type RDAPBootstrap struct {
+	Publication string
+	services    map[string][]string
+	// embedded 表示数据来自内嵌文件，查不到 TLD 时值得从 IANA 下载最新版本
+	embedded bool
+}
+
+// rdapBootstrapFile IANA 引导文件格式
+type rdapBootstrapFile struct {
+	Description string       `json:"description"`
+	Publication string       `json:"publication"`
+	Services    [][][]string `json:"services"`
+	Version     string       `json:"version"`
+}
+
+// ParseRDAPBootstrap 解析 IANA 格式的 RDAP 引导文件
+func ParseRDAPBootstrap(data []byte) (*RDAPBootstrap, error) {
+	var file rdapBootstrapFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	bootstrap := &RDAPBootstrap{
+		Publication: file.Publication,
+		services:    make(map[string][]string),
+	}
+
+	for _, service := range file.Services {
+		if len(service) < 2 {
+			continue
+		}
+		for _, tld := range service[0] {
+			bootstrap.services[strings.ToLower(tld)] = service[1]
+		}
+	}
+
+	if len(bootstrap.services) == 0 {
+		return nil, fmt.Errorf("rdap bootstrap contains no services")
+	}
+
+	return bootstrap, nil
+}
+
+// LoadRDAPBootstrap 加载 RDAP 引导文件，优先使用本地缓存，否则使用内嵌数据
+func LoadRDAPBootstrap() (*RDAPBootstrap, error) {
+	if path, err := rdapBootstrapCachePath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			if bootstrap, err := ParseRDAPBootstrap(data); err == nil {
+				return bootstrap, nil
+			}
+		}
+	}
+
+	bootstrap, err := ParseRDAPBootstrap(rdapBootstrapData)
+	if err != nil {
+		return nil, &TldsFileError{
+			Path: "embedded rdap_dns.json",
+			Err:  err,
+		}
+	}
+	bootstrap.embedded = true
+	return bootstrap, nil
+}
+
+// DownloadRDAPBootstrap 从 IANA 下载最新的 RDAP 引导文件并写入本地缓存
+func DownloadRDAPBootstrap(ctx context.Context, httpClient *http.Client) (*RDAPBootstrap, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ianaRDAPBootstrapURL, nil)
+	if err != nil {
+		return nil, &RDAPError{URL: ianaRDAPBootstrapURL, Err: err}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, &RDAPError{URL: ianaRDAPBootstrapURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &RDAPError{URL: ianaRDAPBootstrapURL, StatusCode: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRDAPResponseSize))
+	if err != nil {
+		return nil, &RDAPError{URL: ianaRDAPBootstrapURL, Err: err}
+	}
+
+	bootstrap, err := ParseRDAPBootstrap(data)
+	if err != nil {
+		return nil, &RDAPError{URL: ianaRDAPBootstrapURL, Err: err}
+	}
+
+	// 缓存写入失败不影响本次使用
+	if path, err := rdapBootstrapCachePath(); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			_ = os.WriteFile(path, data, 0o644)
+		}
+	}
+
+	return bootstrap, nil
+}
+
+// rdapBootstrapCachePath 本地缓存的引导文件路径
+func rdapBootstrapCachePath() (string, error) {
+	path, err := DefaultTLDCachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "rdap_dns.json"), nil
+}
+
+// GetServers 获取指定 TLD 的 RDAP 服务地址
+func (b *RDAPBootstrap) GetServers(tld string) ([]string, bool) {
+	servers, ok := b.services[strings.ToLower(tld)]
+	return servers, ok
+}
@@ -0,0 +1,173 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRDAPClient 创建指向 httptest 服务器的 RDAP 客户端，.test 后缀由该服务器负责
+func newTestRDAPClient(t *testing.T, handler http.Handler) (*RDAPClient, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewRDAPClient(5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("NewRDAPClient: %v", err)
+	}
+	client.SetBootstrap(&RDAPBootstrap{services: map[string][]string{"test": {server.URL + "/"}}})
+	return client, server
+}
+
+const rdapRegistryResponse = `{
+  "ldhName": "EXAMPLE.TEST",
+  "events": [
+    {"eventAction": "registration", "eventDate": "2001-02-03T00:00:00Z"},
+    {"eventAction": "expiration", "eventDate": "2031-02-03T00:00:00Z"}
+  ],
+  "nameservers": [{"ldhName": "NS1.EXAMPLE.TEST"}, {"ldhName": "NS2.EXAMPLE.TEST"}],
+  "entities": [
+    {"roles": ["registrar"], "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Registry View Registrar"]]]}
+  ],
+  "links": [
+    {"rel": "self", "href": "%SELF%", "type": "application/rdap+json"},
+    {"rel": "related", "href": "%REGISTRAR%", "type": "application/rdap+json"}
+  ]
+}`
+
+const rdapRegistrarResponse = `{
+  "ldhName": "example.test",
+  "entities": [
+    {"roles": ["registrar"], "vcardArray": ["vcard", [["fn", {}, "text", "Example Registrar, Inc."]]]}
+  ]
+}`
+
+func TestRDAPFetch(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       func(w http.ResponseWriter, r *http.Request, serverURL string)
+		wantStatus    string
+		wantRegistrar string
+		wantFollowed  bool
+		wantErr       func(error) bool
+	}{
+		{
+			name: "registered",
+			handler: func(w http.ResponseWriter, r *http.Request, serverURL string) {
+				w.Write([]byte(`{"ldhName": "example.test", "events": [{"eventAction": "registration", "eventDate": "2001-02-03T00:00:00Z"}],
+					"entities": [{"roles": ["registrar"], "vcardArray": ["vcard", [["fn", {}, "text", "Registry View Registrar"]]]}]}`))
+			},
+			wantStatus:    StatusRegistered,
+			wantRegistrar: "Registry View Registrar",
+		},
+		{
+			name: "not found is available",
+			handler: func(w http.ResponseWriter, r *http.Request, serverURL string) {
+				http.Error(w, `{"errorCode": 404}`, http.StatusNotFound)
+			},
+			wantStatus: StatusAvailable,
+		},
+		{
+			name: "rate limited with retry-after",
+			handler: func(w http.ResponseWriter, r *http.Request, serverURL string) {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantErr: func(err error) bool {
+				var rateLimited *RateLimitedError
+				return errors.As(err, &rateLimited) && rateLimited.RetryAfter == 7*time.Second
+			},
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request, serverURL string) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantErr: func(err error) bool {
+				var rdapErr *RDAPError
+				return errors.As(err, &rdapErr) && rdapErr.StatusCode == http.StatusInternalServerError
+			},
+		},
+		{
+			name: "follows registrar link",
+			handler: func(w http.ResponseWriter, r *http.Request, serverURL string) {
+				if r.URL.Path == "/registrar/domain/example.test" {
+					w.Write([]byte(rdapRegistrarResponse))
+					return
+				}
+				body := rdapRegistryResponse
+				body = strings.ReplaceAll(body, "%SELF%", serverURL+"/domain/example.test")
+				body = strings.ReplaceAll(body, "%REGISTRAR%", serverURL+"/registrar/domain/example.test")
+				w.Write([]byte(body))
+			},
+			wantStatus:    StatusRegistered,
+			wantRegistrar: "Example Registrar, Inc.",
+			wantFollowed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var serverURL string
+			client, server := newTestRDAPClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept"); got != "application/rdap+json" {
+					t.Errorf("Accept = %q, want application/rdap+json", got)
+				}
+				tt.handler(w, r, serverURL)
+			}))
+			serverURL = server.URL
+
+			result, err := client.Fetch(context.Background(), "www.example.test")
+			if tt.wantErr != nil {
+				if err == nil || !tt.wantErr(err) {
+					t.Fatalf("Fetch error = %v, want matching error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+
+			if result.Domain != "example.test" || result.Protocol != ProtocolRDAP {
+				t.Errorf("Domain, Protocol = %q, %q, want example.test, rdap", result.Domain, result.Protocol)
+			}
+			if result.RegistryServer != server.URL+"/domain/example.test" {
+				t.Errorf("RegistryServer = %q", result.RegistryServer)
+			}
+			if result.Info.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", result.Info.Status, tt.wantStatus)
+			}
+			if result.Info.Registrar != tt.wantRegistrar {
+				t.Errorf("Registrar = %q, want %q", result.Info.Registrar, tt.wantRegistrar)
+			}
+			if followed := result.RegistrarServer != ""; followed != tt.wantFollowed {
+				t.Errorf("RegistrarServer = %q, want followed = %v", result.RegistrarServer, tt.wantFollowed)
+			}
+			if tt.wantFollowed {
+				// 注册商数据中没有的字段回退到注册局数据
+				if result.Info.CreationDate != "2001-02-03T00:00:00Z" {
+					t.Errorf("CreationDate = %q", result.Info.CreationDate)
+				}
+				if want := []string{"ns1.example.test", "ns2.example.test"}; !slices.Equal(result.Info.NameServers, want) {
+					t.Errorf("NameServers = %v, want %v", result.Info.NameServers, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRDAPFetchNoServer(t *testing.T) {
+	client, _ := newTestRDAPClient(t, http.NotFoundHandler())
+
+	_, err := client.Fetch(context.Background(), "example.invalidtld")
+	var noServer *NoRDAPServerFoundError
+	if !errors.As(err, &noServer) {
+		t.Fatalf("Fetch error = %v, want NoRDAPServerFoundError", err)
+	}
+}
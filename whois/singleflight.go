@@ -0,0 +1,65 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// callGroup 对相同 key 的并发调用去重，只执行一次并共享结果
+type callGroup struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// call 一次进行中或已完成的调用
+type call struct {
+	done chan struct{}
+	val  string
+	err  error
+}
+
+// Do 执行 fn，若相同 key 的调用正在进行则等待其结果
+// fn 使用发起调用者的 ctx，发起者取消时共享的结果是取消错误；
+// 等待者自己的 ctx 仍然有效时不接受这个结果，而是重新发起调用。ctx 取消时等待者立即返回
+func (g *callGroup) Do(ctx context.Context, key string, fn func() (string, error)) (string, error) {
+	for {
+		g.mu.Lock()
+		if g.calls == nil {
+			g.calls = make(map[string]*call)
+		}
+		c, ok := g.calls[key]
+		if !ok {
+			break
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-c.done:
+		}
+		if isContextError(c.err) && ctx.Err() == nil {
+			continue
+		}
+		return c.val, c.err
+	}
+
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+
+	return c.val, c.err
+}
+
+// isContextError 判断错误是否由 ctx 取消或超时引起
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,113 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallGroupShares(t *testing.T) {
+	var g callGroup
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		g.Do(context.Background(), "com", func() (string, error) {
+			calls.Add(1)
+			<-release
+			return "whois.verisign-grs.com", nil
+		})
+	}()
+	waitForCall(t, &g, "com")
+
+	result := make(chan string)
+	go func() {
+		val, _ := g.Do(context.Background(), "com", func() (string, error) {
+			calls.Add(1)
+			return "unexpected", nil
+		})
+		result <- val
+	}()
+
+	// 给等待者一点时间进入等待
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if got := <-result; got != "whois.verisign-grs.com" {
+		t.Errorf("waiter got %q", got)
+	}
+	<-leaderDone
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn called %d times, want 1", n)
+	}
+}
+
+func TestCallGroupLeaderCanceled(t *testing.T) {
+	var g callGroup
+	leaderCtx, cancel := context.WithCancel(context.Background())
+
+	go g.Do(leaderCtx, "com", func() (string, error) {
+		<-leaderCtx.Done()
+		return "", leaderCtx.Err()
+	})
+	waitForCall(t, &g, "com")
+
+	result := make(chan error)
+	go func() {
+		val, err := g.Do(context.Background(), "com", func() (string, error) {
+			return "whois.verisign-grs.com", nil
+		})
+		if err == nil && val != "whois.verisign-grs.com" {
+			err = errors.New("unexpected value " + val)
+		}
+		result <- err
+	}()
+
+	// 发起者取消后，等待者应当自己重新查询而不是得到取消错误
+	cancel()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("waiter error = %v, want retry to succeed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter did not return")
+	}
+}
+
+func TestCallGroupWaiterCanceled(t *testing.T) {
+	var g callGroup
+	release := make(chan struct{})
+	defer close(release)
+
+	go g.Do(context.Background(), "com", func() (string, error) {
+		<-release
+		return "whois.verisign-grs.com", nil
+	})
+	waitForCall(t, &g, "com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.Do(ctx, "com", func() (string, error) { return "", nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled waiter error = %v, want context.Canceled", err)
+	}
+}
+
+// waitForCall 等待 key 的调用开始
+func waitForCall(t *testing.T, g *callGroup, key string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		_, ok := g.calls[key]
+		g.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("call for %s did not start", key)
+}
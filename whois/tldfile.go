@@ -0,0 +1,194 @@
+package whois
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// IANA 发布的顶级域名列表地址
+const ianaTLDListURL = "https://data.iana.org/TLD/tlds-alpha-by-domain.txt"
+
+// TLDChange 两份 TLD 映射之间的一处差异
+type TLDChange struct {
+	TLD string
+	Old string // 为空表示新增
+	New string // 为空表示删除
+}
+
+// EmbeddedTLDs 返回构建时内嵌的 TLD 映射副本
+func EmbeddedTLDs() (map[string]string, error) {
+	tlds, err := parseTLDMap(tldsData)
+	if err != nil {
+		return nil, &TldsFileError{Path: "embedded tlds.json", Err: err}
+	}
+	return tlds, nil
+}
+
+// DefaultTLDOverridePath 返回默认的用户 TLD 覆盖文件路径，例如 ~/.config/gois/tlds.json
+func DefaultTLDOverridePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gois", "tlds.json"), nil
+}
+
+// DefaultTLDRefreshPath 返回 tld refresh 默认写入的文件路径，例如 ~/.cache/gois/tlds-iana.json
+// 该文件取代内嵌映射作为基础层，与用户覆盖文件分开保存，刷新不会改动用户的设置
+func DefaultTLDRefreshPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gois", "tlds-iana.json"), nil
+}
+
+// LoadTLDFile 读取 TLD 映射文件，.yaml/.yml 按 YAML 解析，其余按 JSON 解析
+func LoadTLDFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &TldsFileError{Path: path, Err: err}
+	}
+
+	var tlds map[string]string
+	if isYAMLFile(path) {
+		tlds, err = parseTLDYAML(data)
+	} else {
+		tlds, err = parseTLDMap(data)
+	}
+	if err != nil {
+		return nil, &TldsFileError{Path: path, Err: err}
+	}
+	return tlds, nil
+}
+
+// SaveTLDFile 写入 TLD 映射文件，格式由扩展名决定；先写临时文件再重命名以避免写坏
+func SaveTLDFile(path string, tlds map[string]string) error {
+	var data []byte
+	if isYAMLFile(path) {
+		data = formatTLDYAML(tlds)
+	} else {
+		var err error
+		data, err = json.MarshalIndent(tlds, "", "    ")
+		if err != nil {
+			return &TldsFileError{Path: path, Err: err}
+		}
+		data = append(data, '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return &TldsFileError{Path: path, Err: err}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return &TldsFileError{Path: path, Err: err}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return &TldsFileError{Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// DiffTLDs 比较两份 TLD 映射，按 TLD 排序返回新增、删除和服务器变化的条目
+func DiffTLDs(base, current map[string]string) []TLDChange {
+	var changes []TLDChange
+	for tld, server := range current {
+		if old, ok := base[tld]; !ok || old != server {
+			changes = append(changes, TLDChange{TLD: tld, Old: old, New: server})
+		}
+	}
+	for tld, old := range base {
+		if _, ok := current[tld]; !ok {
+			changes = append(changes, TLDChange{TLD: tld, Old: old})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].TLD < changes[j].TLD
+	})
+	return changes
+}
+
+// DownloadIANATLDList 下载 IANA 的顶级域名列表，返回小写的 A-label TLD
+func DownloadIANATLDList(ctx context.Context, httpClient *http.Client) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ianaTLDListURL, nil)
+	if err != nil {
+		return nil, NewWhoisError("failed to download iana tld list", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, NewWhoisError("failed to download iana tld list", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewWhoisError(fmt.Sprintf("failed to download iana tld list: unexpected status %d", resp.StatusCode), nil)
+	}
+
+	var tlds []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tlds = append(tlds, strings.ToLower(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NewWhoisError("failed to download iana tld list", err)
+	}
+
+	return tlds, nil
+}
+
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// parseTLDYAML 解析 YAML 映射，顶层必须是 tld: server 形式的映射
+func parseTLDYAML(data []byte) (map[string]string, error) {
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	tlds := make(map[string]string, len(raw))
+	for key, value := range raw {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("empty tld")
+		}
+		tlds[toASCII(strings.ToLower(key))] = strings.TrimSpace(value)
+	}
+	return tlds, nil
+}
+
+// formatTLDYAML 将 TLD 映射格式化为按键排序的 YAML
+func formatTLDYAML(tlds map[string]string) []byte {
+	keys := make([]string, 0, len(tlds))
+	for tld := range tlds {
+		keys = append(keys, tld)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, tld := range keys {
+		fmt.Fprintf(&buf, "%s: %s\n", strconv.Quote(tld), strconv.Quote(tlds[tld]))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,88 @@
+package whois
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadTLDFileYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "flat",
+			content: "# comment\n---\ncom: whois.verisign-grs.com  # trailing\n\"中国\": 'cwhois.cnnic.cn'\nCO.UK: whois.nic.uk\n",
+			want:    map[string]string{"com": "whois.verisign-grs.com", "xn--fiqs8s": "cwhois.cnnic.cn", "co.uk": "whois.nic.uk"},
+		},
+		{
+			name:    "flow mapping",
+			content: "{io: whois.nic.io, de: whois.denic.de}\n",
+			want:    map[string]string{"io": "whois.nic.io", "de": "whois.denic.de"},
+		},
+		{
+			name:    "anchor and folded scalar",
+			content: "net: &verisign whois.verisign-grs.com\ncom: *verisign\nde: >-\n  whois.denic.de\n",
+			want:    map[string]string{"net": "whois.verisign-grs.com", "com": "whois.verisign-grs.com", "de": "whois.denic.de"},
+		},
+		{name: "nested mapping", content: "tlds:\n  com: whois.verisign-grs.com\n", wantErr: true},
+		{name: "list", content: "- com\n- net\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tlds.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadTLDFile(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LoadTLDFile = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadTLDFile: %v", err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("LoadTLDFile = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveTLDFileRoundTrip(t *testing.T) {
+	tlds := map[string]string{"com": "whois.verisign-grs.com", "xn--fiqs8s": "cwhois.cnnic.cn", "io": ""}
+	for _, name := range []string{"tlds.json", "tlds.yaml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := SaveTLDFile(path, tlds); err != nil {
+			t.Fatalf("SaveTLDFile(%s): %v", name, err)
+		}
+		got, err := LoadTLDFile(path)
+		if err != nil {
+			t.Fatalf("LoadTLDFile(%s): %v", name, err)
+		}
+		if !maps.Equal(got, tlds) {
+			t.Errorf("%s round trip = %v, want %v", name, got, tlds)
+		}
+	}
+}
+
+func TestDiffTLDs(t *testing.T) {
+	base := map[string]string{"com": "a", "net": "b", "org": "c"}
+	current := map[string]string{"com": "a", "net": "x", "io": "d"}
+
+	var got []string
+	for _, change := range DiffTLDs(base, current) {
+		got = append(got, change.TLD+":"+change.Old+">"+change.New)
+	}
+	if want := []string{"io:>d", "net:b>x", "org:c>"}; !slices.Equal(got, want) {
+		t.Errorf("DiffTLDs = %v, want %v", got, want)
+	}
+}
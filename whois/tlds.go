@@ -3,62 +3,206 @@ package whois
 import (
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
 )
 
 //go:embed tlds.json
 var tldsData []byte
 
-// TLDRegistry 管理 TLD 到 WHOIS 服务器的映射
+// TLDRegistry 管理 TLD 到 WHOIS 服务器的映射，可安全地并发使用
 type TLDRegistry struct {
+	mu   sync.RWMutex
 	tlds map[string]string
+	// learned 从 IANA 学习到的服务器，启用缓存文件时会持久化
+	learned   map[string]string
+	cachePath string
+	// saveLock 串行化缓存文件写入
+	saveLock sync.Mutex
 }
 
 // NewTLDRegistry 创建一个新的 TLD 注册表
 func NewTLDRegistry() (*TLDRegistry, error) {
-	registry := &TLDRegistry{
-		tlds: make(map[string]string),
-	}
-
-	// 先尝试解析为 map[string]interface{} 以处理可能的非字符串值
-	var rawData map[string]interface{}
-	if err := json.Unmarshal(tldsData, &rawData); err != nil {
+	tlds, err := parseTLDMap(tldsData)
+	if err != nil {
 		return nil, &TldsFileError{
 			Path: "embedded tlds.json",
 			Err:  err,
 		}
 	}
 
-	// 转换所有值为字符串
+	return &TLDRegistry{
+		tlds:    tlds,
+		learned: make(map[string]string),
+	}, nil
+}
+
+// parseTLDMap 解析 TLD 到 WHOIS 服务器的 JSON 映射
+func parseTLDMap(data []byte) (map[string]string, error) {
+	// 先尝试解析为 map[string]interface{} 以处理可能的非字符串值
+	var rawData map[string]interface{}
+	if err := json.Unmarshal(data, &rawData); err != nil {
+		return nil, err
+	}
+
+	// 转换所有值为字符串，键统一为 A-label 以匹配 punycode 查询
+	tlds := make(map[string]string, len(rawData))
 	for key, value := range rawData {
+		key = toASCII(key)
 		switch v := value.(type) {
 		case string:
-			registry.tlds[key] = v
+			tlds[key] = v
 		case float64:
-			registry.tlds[key] = fmt.Sprintf("%.0f", v)
+			tlds[key] = fmt.Sprintf("%.0f", v)
 		case int:
-			registry.tlds[key] = fmt.Sprintf("%d", v)
+			tlds[key] = fmt.Sprintf("%d", v)
 		default:
-			registry.tlds[key] = fmt.Sprintf("%v", v)
+			tlds[key] = fmt.Sprintf("%v", v)
 		}
 	}
 
-	return registry, nil
+	return tlds, nil
+}
+
+// DefaultTLDCachePath 返回默认的 TLD 缓存文件路径，例如 ~/.cache/gois/tlds.json
+func DefaultTLDCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gois", "tlds.json"), nil
+}
+
+// LoadBase 读取 tld refresh 生成的完整映射并替换内嵌映射
+// 需要在 EnableCache 和 LoadOverrides 之前调用，缓存和用户覆盖仍然叠加在它之上
+func (r *TLDRegistry) LoadBase(path string) error {
+	base, err := LoadTLDFile(path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tlds = base
+	return nil
+}
+
+// EnableCache 启用缓存文件：已有的缓存内容覆盖内嵌数据，之后从 IANA 学习到的服务器会写回该文件
+func (r *TLDRegistry) EnableCache(path string) error {
+	cached, err := LoadTLDFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cachePath = path
+	for tld, server := range cached {
+		r.tlds[tld] = server
+		r.learned[tld] = server
+	}
+
+	return nil
 }
 
 // GetWhoisServer 获取指定 TLD 的 WHOIS 服务器
 func (r *TLDRegistry) GetWhoisServer(tld string) (string, bool) {
-	server, ok := r.tlds[tld]
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	server, ok := r.tlds[toASCII(tld)]
 	return server, ok
 }
 
+// LookupSuffix 按从长到短的后缀候选查找 WHOIS 服务器，例如 co.uk 没有映射时使用 uk 的服务器
+// 返回实际匹配的后缀和服务器
+func (r *TLDRegistry) LookupSuffix(suffix string) (string, string, bool) {
+	for _, candidate := range suffixCandidates(toASCII(suffix)) {
+		if server, ok := r.GetWhoisServer(candidate); ok {
+			return candidate, server, true
+		}
+	}
+	return "", "", false
+}
+
 // SetWhoisServer 设置指定 TLD 的 WHOIS 服务器
 func (r *TLDRegistry) SetWhoisServer(tld, server string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tlds[toASCII(tld)] = server
+}
+
+// LearnWhoisServer 记录从 IANA 学习到的 WHOIS 服务器，启用缓存时同步写入缓存文件
+func (r *TLDRegistry) LearnWhoisServer(tld, server string) error {
+	tld = toASCII(tld)
+
+	r.mu.Lock()
 	r.tlds[tld] = server
+	r.learned[tld] = server
+	cachePath := r.cachePath
+	r.mu.Unlock()
+
+	if cachePath == "" {
+		return nil
+	}
+	return r.saveCache(cachePath)
+}
+
+// saveCache 将学习到的服务器写入缓存文件
+func (r *TLDRegistry) saveCache(path string) error {
+	r.saveLock.Lock()
+	defer r.saveLock.Unlock()
+
+	r.mu.RLock()
+	learned := make(map[string]string, len(r.learned))
+	for tld, server := range r.learned {
+		learned[tld] = server
+	}
+	r.mu.RUnlock()
+
+	return SaveTLDFile(path, learned)
+}
+
+// LoadOverrides 读取用户的 TLD 覆盖文件（JSON 或 YAML），合并到当前映射之上
+func (r *TLDRegistry) LoadOverrides(path string) error {
+	overrides, err := LoadTLDFile(path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tld, server := range overrides {
+		r.tlds[tld] = server
+	}
+	return nil
+}
+
+// Snapshot 返回当前 TLD 映射的副本
+func (r *TLDRegistry) Snapshot() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tlds := make(map[string]string, len(r.tlds))
+	for tld, server := range r.tlds {
+		tlds[tld] = server
+	}
+	return tlds
 }
 
 // GetAllTLDs 获取所有已知的 TLD
 func (r *TLDRegistry) GetAllTLDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	tlds := make([]string, 0, len(r.tlds))
 	for tld := range r.tlds {
 		tlds = append(tlds, tld)